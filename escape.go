@@ -1,5 +1,7 @@
 package sqlschema
 
+// Escape a string literal by the rules of MySQL, the escaped string is quoted with single quotes.
+// The control characters are escaped as their escape sequences, UTF-8 text is kept as is.
 func escape(source string) string {
 	var j int = 0
 	if len(source) == 0 {
@@ -11,12 +13,15 @@ func escape(source string) string {
 		flag := false
 		var escape byte
 		switch tempStr[i] {
+		case '\x00':
+			flag = true
+			escape = '0'
 		case '\r':
 			flag = true
-			escape = '\r'
+			escape = 'r'
 		case '\n':
 			flag = true
-			escape = '\n'
+			escape = 'n'
 		case '\\':
 			flag = true
 			escape = '\\'
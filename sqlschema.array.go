@@ -0,0 +1,109 @@
+package sqlschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Escape the backslash and the delimiter in an array item with a leading backslash
+func escapeArrayItem(item string, delimiter string) string {
+	if delimiter == "" {
+		return item
+	}
+	item = strings.ReplaceAll(item, `\`, `\\`)
+	return strings.ReplaceAll(item, delimiter, `\`+delimiter)
+}
+
+// Split the data by the delimiter which is not escaped, and unescape the items
+func splitArray(data string, delimiter string) []string {
+	parts := make([]string, 0, 4)
+	item := strings.Builder{}
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\\' && i+1 < len(data) {
+			item.WriteByte(data[i+1])
+			i++
+		} else if delimiter != "" && strings.HasPrefix(data[i:], delimiter) {
+			parts = append(parts, item.String())
+			item.Reset()
+			i += len(delimiter) - 1
+		} else {
+			item.WriteByte(data[i])
+		}
+	}
+	return append(parts, item.String())
+}
+
+// Encode a slice of numbers or strings into a string joined by the delimiter
+func encodeArray(v reflect.Value, delimiter string) (string, error) {
+	if v.Kind() != reflect.Slice {
+		return "", errors.Errorf("unsupported array type %s", v.Type())
+	}
+	parts := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		switch item.Kind() {
+		case reflect.String:
+			parts[i] = escapeArrayItem(item.String(), delimiter)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			parts[i] = strconv.FormatInt(item.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			parts[i] = strconv.FormatUint(item.Uint(), 10)
+		case reflect.Float32, reflect.Float64:
+			parts[i] = strconv.FormatFloat(item.Float(), 'g', -1, item.Type().Bits())
+		case reflect.Bool:
+			parts[i] = strconv.FormatBool(item.Bool())
+		default:
+			return "", errors.Errorf("unsupported array element type %s", item.Type())
+		}
+	}
+	return strings.Join(parts, delimiter), nil
+}
+
+// Decode a string joined by the delimiter into a slice of type t, an empty string is decoded to a nil slice
+func decodeArray(t reflect.Type, data string, delimiter string) (reflect.Value, error) {
+	if t.Kind() != reflect.Slice {
+		return reflect.Zero(t), errors.Errorf("unsupported array type %s", t)
+	}
+	if data == "" {
+		return reflect.Zero(t), nil
+	}
+	parts := splitArray(data, delimiter)
+	arr := reflect.MakeSlice(t, len(parts), len(parts))
+	for i, part := range parts {
+		item := arr.Index(i)
+		switch item.Kind() {
+		case reflect.String:
+			item.SetString(part)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, e := strconv.ParseInt(part, 10, item.Type().Bits())
+			if e != nil {
+				return arr, e
+			}
+			item.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, e := strconv.ParseUint(part, 10, item.Type().Bits())
+			if e != nil {
+				return arr, e
+			}
+			item.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			f, e := strconv.ParseFloat(part, item.Type().Bits())
+			if e != nil {
+				return arr, e
+			}
+			item.SetFloat(f)
+		case reflect.Bool:
+			b, e := strconv.ParseBool(part)
+			if e != nil {
+				return arr, e
+			}
+			item.SetBool(b)
+		default:
+			return arr, errors.Errorf("unsupported array element type %s", item.Type())
+		}
+	}
+	return arr, nil
+}
@@ -2,62 +2,30 @@ package sqlschema
 
 import (
 	"context"
-	"database/sql"
+	"strings"
 )
 
-func (sc *Schema) Create(db *sql.DB, ctx context.Context) error {
-	var err error
-	var sql string
-	var args []interface{}
-
-	sql = "CREATE TABLE IF NOT EXISTS `" + sc.Name + "` ("
-	for _, field := range sc.Fields {
-		sql += "`" + field.Name + "` " + field.Type
-		if field.Nullable {
-			sql += " NULL"
-		} else {
-			sql += " NOT NULL"
-		}
-		if field.AutoIncrement {
-			sql += " AUTO_INCREMENT"
-		}
-		if field.DefaultValue != "" {
-			sql += " DEFAULT " + field.DefaultValue
-		}
-		if field.Comment != "" {
-			sql += " COMMENT '" + escape(field.Comment) + "'"
-		}
-		sql += ","
-	}
-	for _, index := range sc.Indices {
-		if index.Primary {
-			sql += "PRIMARY KEY ("
-		} else if index.Unique {
-			sql += "UNIQUE KEY `" + index.Name + "` ("
-		} else {
-			sql += "KEY `" + index.Name + "` ("
-		}
-		for _, column := range index.Columns {
-			sql += "`" + column + "`,"
-		}
-		sql = sql[:len(sql)-1] + "),"
-	}
-	sql = sql[:len(sql)-1] + ")"
-	if sc.Engine != "" {
-		sql += " ENGINE=" + sc.Engine
-	}
-
-	if sc.Collate != "" {
-		sql += " COLLATE=" + sc.Collate
+// Create creates the table if it does not exist. Like the other functions taking an Executor, db could be a *sql.Conn
+// to run the statements on a pinned session, e.g. after SET FOREIGN_KEY_CHECKS=0.
+func (sc *Schema) Create(db Executor, ctx context.Context) error {
+	stmts, err := sc.dialect().CreateTable(sc)
+	if err != nil {
+		return err
 	}
 
-	if sc.Comment != "" {
-		sql += " COMMENT='" + escape(sc.Comment) + "'"
+	for _, stmt := range stmts {
+		if _, err = execLogged(ctx, db.ExecContext, stmt); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	_, err = db.ExecContext(ctx, sql, args...)
+// CreateSQL returns the statements Create would execute, separated by ";\n"
+func (sc *Schema) CreateSQL() (string, error) {
+	stmts, err := sc.dialect().CreateTable(sc)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	return strings.Join(stmts, ";\n"), nil
 }
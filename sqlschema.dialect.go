@@ -0,0 +1,286 @@
+package sqlschema
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dialect hides the differences between database servers, it's responsible for
+// generating the DDL statements and reading the schema of an existing table.
+// The statements are generated from the Schema and Field definitions, the type of
+// a field is always written in MySQL syntax, the dialect maps it to its own types.
+type Dialect interface {
+	// QuoteIdent quotes an identifier like a table or column name
+	QuoteIdent(name string) string
+	// QuoteString quotes a string literal
+	QuoteString(s string) string
+	// Placeholder returns the placeholder of the nth argument of a statement, n starts from 1
+	Placeholder(n int) string
+	// ColumnType maps the type of the field to the type used by the dialect
+	ColumnType(field *Field) string
+	// AutoIncrementClause returns the clause appended to the definition of an auto increment column
+	AutoIncrementClause(field *Field) string
+	// TransactionalDDL reports whether the DDL statements could be rolled back in a transaction
+	TransactionalDDL() bool
+	// UpsertClause returns the clause appended to an INSERT statement to update the columns of
+	// the existing row when the keys conflict
+	UpsertClause(keys []string, columns []string) string
+	// InsertIgnore turns an INSERT statement into one keeping the existing row when the keys conflict
+	InsertIgnore(query string) string
+	// ReturningClause returns the clause appended to an INSERT statement to return the values of the columns
+	// generated by the server, it's empty if the dialect reads the auto increment value by LastInsertId
+	ReturningClause(columns []string) string
+
+	// CreateTable returns the statements to create the table
+	CreateTable(sc *Schema) ([]string, error)
+	// AlterTable returns the statements to change the table options from cur to sc
+	AlterTable(sc *Schema, cur *Schema) ([]string, error)
+	// AddColumn returns the statements to add the field to the table
+	AddColumn(sc *Schema, field *Field) ([]string, error)
+	// ModifyColumn returns the statements to change the column from cur to field
+	ModifyColumn(sc *Schema, field *Field, cur *Field) ([]string, error)
+	// RenameColumn returns the statements to rename the column cur to field and change its definition
+	RenameColumn(sc *Schema, field *Field, cur *Field) ([]string, error)
+	// DropColumn returns the statements to drop the column from the table
+	DropColumn(sc *Schema, field *Field) ([]string, error)
+	// AddIndex returns the statements to add the index to the table
+	AddIndex(sc *Schema, index *Index) ([]string, error)
+	// ModifyIndex returns the statements to change the index from cur to index
+	ModifyIndex(sc *Schema, index *Index, cur *Index) ([]string, error)
+	// DropIndex returns the statements to drop the index from the table
+	DropIndex(sc *Schema, index *Index) ([]string, error)
+	// AddForeignKey returns the statements to add the foreign key constraint to the table
+	AddForeignKey(sc *Schema, fk *ForeignKey) ([]string, error)
+	// DropForeignKey returns the statements to drop the foreign key constraint from the table
+	DropForeignKey(sc *Schema, fk *ForeignKey) ([]string, error)
+	// AddCheck returns the statements to add the check constraint to the table
+	AddCheck(sc *Schema, ck *Check) ([]string, error)
+	// DropCheck returns the statements to drop the check constraint from the table
+	DropCheck(sc *Schema, ck *Check) ([]string, error)
+	// ForeignKeyChecks returns the statements to disable the foreign key checks of the session and to restore them
+	ForeignKeyChecks() ([]string, []string, error)
+
+	// ReadTable reads the table options into sc, returns false if the table does not exist
+	ReadTable(db Executor, ctx context.Context, sc *Schema) (bool, error)
+	// ReadColumns reads the columns of the table into sc.Fields
+	ReadColumns(db Executor, ctx context.Context, sc *Schema) error
+	// ReadIndices reads the indices of the table into sc.Indices
+	ReadIndices(db Executor, ctx context.Context, sc *Schema) error
+	// ReadForeignKeys reads the foreign key constraints of the table into sc.ForeignKeys
+	ReadForeignKeys(db Executor, ctx context.Context, sc *Schema) error
+	// ReadChecks reads the check constraints of the table into sc.Checks, returns ErrNotSupported if the
+	// server does not report them
+	ReadChecks(db Executor, ctx context.Context, sc *Schema) error
+}
+
+// DefaultDialect is used by schemas without a Dialect
+var DefaultDialect Dialect = MySQL
+
+func (sc *Schema) dialect() Dialect {
+	if sc.Dialect != nil {
+		return sc.Dialect
+	}
+	return DefaultDialect
+}
+
+// Split a column type like `bigint(20) unsigned` into its name, parameter and modifiers: bigint, 20, unsigned
+func splitColumnType(t string) (string, string, string) {
+	t = strings.TrimSpace(t)
+	name, modifiers := t, ""
+	if i := strings.IndexAny(t, "( "); i >= 0 {
+		name, modifiers = t[:i], t[i:]
+	}
+	param := ""
+	if strings.HasPrefix(modifiers, "(") {
+		if i := strings.Index(modifiers, ")"); i >= 0 {
+			param = modifiers[1:i]
+			modifiers = modifiers[i+1:]
+		}
+	}
+	return strings.ToLower(name), param, strings.TrimSpace(modifiers)
+}
+
+var defaultExpressions = map[string]bool{
+	"NULL":              true,
+	"TRUE":              true,
+	"FALSE":             true,
+	"CURRENT_TIMESTAMP": true,
+	"CURRENT_DATE":      true,
+	"CURRENT_TIME":      true,
+	"LOCALTIME":         true,
+	"LOCALTIMESTAMP":    true,
+	"NOW":               true,
+}
+
+// Check whether a default value is an expression like CURRENT_TIMESTAMP, NOW() or (uuid()) rather than a literal.
+// An expression is either wrapped in parentheses or a function call, the other values with parentheses are literals.
+func isDefaultExpression(v string) bool {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "(") {
+		return skipGroup(v, 0) == len(v)
+	}
+	if i := strings.Index(v, "("); i > 0 && validIdentifier(v[:i]) {
+		return skipGroup(v, i) == len(v)
+	}
+	return defaultExpressions[strings.ToUpper(v)]
+}
+
+func isSpatialType(t string) bool {
+	name, _, _ := splitColumnType(t)
+	switch name {
+	case "point", "linestring", "polygon", "geometry", "multipoint", "multilinestring", "multipolygon", "geometrycollection", "geomcollection":
+		return true
+	}
+	return false
+}
+
+func isNumericType(t string) bool {
+	name, _, _ := splitColumnType(t)
+	switch name {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "float", "double", "real", "decimal", "numeric", "bit", "bool", "boolean":
+		return true
+	}
+	return false
+}
+
+// Format the default value of the field for DDL, literals of non-numeric columns are quoted and escaped,
+// numbers, expressions and values quoted by the user are kept as is.
+func formatDefault(d Dialect, field *Field) string {
+	v := field.DefaultValue
+	if strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") && len(v) >= 2 {
+		return v
+	}
+	if isDefaultExpression(v) {
+		return v
+	}
+	if _, e := strconv.ParseFloat(v, 64); e == nil && isNumericType(field.Type) {
+		return v
+	}
+	return d.QuoteString(v)
+}
+
+// The constraint clause of a foreign key, shared by the dialects using the standard syntax
+func foreignKeyDefinition(d Dialect, sc *Schema, fk *ForeignKey) string {
+	sql := "CONSTRAINT " + d.QuoteIdent(sc.ForeignKeyName(fk)) + " FOREIGN KEY (" + quoteColumns(d, fk.Columns) + ") REFERENCES " + quoteSchemaTable(d, fk.RefTable) + " (" + quoteColumns(d, fk.RefColumns) + ")"
+	if fk.OnDelete != "" {
+		sql += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		sql += " ON UPDATE " + fk.OnUpdate
+	}
+	return sql
+}
+
+// The constraint clause of a check, shared by all the dialects
+func checkDefinition(d Dialect, sc *Schema, ck *Check) string {
+	return "CONSTRAINT " + d.QuoteIdent(sc.CheckName(ck)) + " CHECK (" + ck.Expression + ")"
+}
+
+// The ON CONFLICT clause shared by PostgreSQL and SQLite
+func onConflictClause(d Dialect, keys []string, columns []string) string {
+	if len(columns) == 0 {
+		return " ON CONFLICT (" + quoteColumns(d, keys) + ") DO NOTHING"
+	}
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, d.QuoteIdent(column)+"=EXCLUDED."+d.QuoteIdent(column))
+	}
+	return " ON CONFLICT (" + quoteColumns(d, keys) + ") DO UPDATE SET " + strings.Join(sets, ",")
+}
+
+// Check the identifier only contains letters, digits and underscores
+func validIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate and quote a table name given by the caller, the name could be in the form of schema.table
+func quoteTableName(d Dialect, table string) (string, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) > 2 {
+		return "", errors.Wrapf(ErrInvalidIdentifier, "Invalid table name %s", table)
+	}
+	for i, part := range parts {
+		if !validIdentifier(part) {
+			return "", errors.Wrapf(ErrInvalidIdentifier, "Invalid table name %s", table)
+		}
+		parts[i] = d.QuoteIdent(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// Refuse the partitioned tables, for the dialects without partitioning
+func checkPartitioning(sc *Schema) error {
+	if sc.Partitioning != nil {
+		return errors.Wrapf(ErrNotSupported, "Partitioning of table %s", sc.Name)
+	}
+	return nil
+}
+
+// Split the name of a schema in the form of schema.table, the schema is empty if not given
+func splitTableName(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Quote the name of an object, like a table or an index, in the schema of the table
+func quoteQualifiedName(d Dialect, table string, name string) string {
+	if schema, _ := splitTableName(table); schema != "" {
+		return d.QuoteIdent(schema) + "." + d.QuoteIdent(name)
+	}
+	return d.QuoteIdent(name)
+}
+
+// Quote the table name of a schema, qualified by the schema if given
+func quoteSchemaTable(d Dialect, name string) string {
+	_, table := splitTableName(name)
+	return quoteQualifiedName(d, name, table)
+}
+
+// Refuse the unique indices treating NULL values as equal, for the dialects without NULLS NOT DISTINCT
+func checkNullsDistinct(indices ...Index) error {
+	for i := range indices {
+		if indices[i].NullsNotDistinct {
+			return errors.Wrapf(ErrNotSupported, "NULLS NOT DISTINCT of index %s", indices[i].Name)
+		}
+	}
+	return nil
+}
+
+// Split an index column like `name(10) DESC` into the column name and the rest of the definition: name, (10) DESC
+func splitIndexColumn(column string) (string, string) {
+	if i := strings.IndexAny(column, "( "); i >= 0 {
+		return column[:i], column[i:]
+	}
+	return column, ""
+}
+
+// Quote the columns of an index, the prefix length and direction of a column are kept as is, e.g. `name`(10) DESC
+func quoteIndexColumns(d Dialect, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		name, rest := splitIndexColumn(column)
+		quoted = append(quoted, d.QuoteIdent(name)+rest)
+	}
+	return strings.Join(quoted, ",")
+}
+
+func quoteColumns(d Dialect, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, d.QuoteIdent(column))
+	}
+	return strings.Join(quoted, ",")
+}
@@ -0,0 +1,320 @@
+package sqlschema
+
+// ColumnChange is a column modified or renamed by a migration
+type ColumnChange struct {
+	Old        Field
+	New        Field
+	TypeChange TypeChange // Whether the values of the column survive the change of its type
+}
+
+// IndexChange is an index modified by a migration
+type IndexChange struct {
+	Old Index
+	New Index
+}
+
+// SchemaDiff is the set of changes to migrate a table from its current schema to the defined one
+type SchemaDiff struct {
+	Schema              *Schema // The defined schema
+	Current             *Schema // The schema read from the database, nil if the table does not exist
+	TableOptionsChanged bool    // Engine, Collate, Comment, RowFormat, AutoIncrement or Partitioning of the table changed
+	AddedColumns        []Field
+	DroppedColumns      []Field
+	ModifiedColumns     []ColumnChange
+	RenamedColumns      []ColumnChange
+	AddedIndices        []Index
+	DroppedIndices      []Index
+	ModifiedIndices     []IndexChange
+	AddedForeignKeys    []ForeignKey
+	DroppedForeignKeys  []ForeignKey
+	AddedChecks         []Check
+	DroppedChecks       []Check // A modified check is dropped and added again
+	RefusedColumns      []Field // Columns not dropped in safe mode
+	RefusedIndices      []Index // Indices not dropped in safe mode
+	// Columns not modified or renamed in safe mode, as their types narrow or change to an incompatible one
+	RefusedModifications []ColumnChange
+
+	dialect Dialect
+}
+
+// Empty reports whether there is nothing to change, the changes refused in safe mode are not counted
+func (diff *SchemaDiff) Empty() bool {
+	return diff.Current != nil && !diff.TableOptionsChanged &&
+		len(diff.AddedColumns) == 0 && len(diff.DroppedColumns) == 0 && len(diff.ModifiedColumns) == 0 && len(diff.RenamedColumns) == 0 &&
+		len(diff.AddedIndices) == 0 && len(diff.DroppedIndices) == 0 && len(diff.ModifiedIndices) == 0 &&
+		len(diff.AddedForeignKeys) == 0 && len(diff.DroppedForeignKeys) == 0 &&
+		len(diff.AddedChecks) == 0 && len(diff.DroppedChecks) == 0
+}
+
+// The dialects giving the NOT NULL timestamps without a default one, the default is compared as if it's defined
+type timestampDefaulter interface {
+	timestampDefault(field *Field) string
+}
+
+// Compare the schema with the current one read from the database
+func (sc *Schema) compare(d Dialect, cur *Schema, opts *UpdateOptions) (*SchemaDiff, error) {
+	diff := &SchemaDiff{Schema: sc, Current: cur, dialect: d}
+	if cur == nil {
+		return diff, nil
+	}
+
+	// Only the renames from an existing column to a defined column are applied
+	renamed := make(map[string]string)
+	for from, to := range opts.Renames {
+		if cur.Field(from) != nil && cur.Field(to) == nil && sc.Field(from) == nil && sc.Field(to) != nil {
+			renamed[from] = to
+		}
+	}
+	renamedFrom := make(map[string]string)
+	for from, to := range renamed {
+		renamedFrom[to] = from
+	}
+	renameColumns := func(columns []string) []string {
+		ret := make([]string, len(columns))
+		for i, column := range columns {
+			name, rest := splitIndexColumn(column)
+			if to, ok := renamed[name]; ok {
+				column = to + rest
+			}
+			ret[i] = column
+		}
+		return ret
+	}
+
+	stmts, e := d.AlterTable(sc, cur)
+	if e != nil {
+		return nil, e
+	}
+	diff.TableOptionsChanged = len(stmts) > 0
+
+	for i := range cur.ForeignKeys {
+		fk := sc.ForeignKey(cur.ForeignKeyName(&cur.ForeignKeys[i]))
+		if fk == nil || !fk.Equal(&cur.ForeignKeys[i]) {
+			diff.DroppedForeignKeys = append(diff.DroppedForeignKeys, cur.ForeignKeys[i])
+		}
+	}
+
+	if !cur.checksUnknown {
+		for i := range cur.Checks {
+			ck := sc.Check(cur.CheckName(&cur.Checks[i]))
+			if ck == nil || !ck.Equal(&cur.Checks[i]) {
+				diff.DroppedChecks = append(diff.DroppedChecks, cur.Checks[i])
+			}
+		}
+		for i := range sc.Checks {
+			ck := cur.Check(sc.CheckName(&sc.Checks[i]))
+			if ck == nil || !ck.Equal(&sc.Checks[i]) {
+				diff.AddedChecks = append(diff.AddedChecks, sc.Checks[i])
+			}
+		}
+	}
+
+	for i := range cur.Fields {
+		if _, ok := renamed[cur.Fields[i].Name]; ok {
+			continue
+		}
+		if sc.Field(cur.Fields[i].Name) == nil {
+			if opts.SafeMode {
+				diff.RefusedColumns = append(diff.RefusedColumns, cur.Fields[i])
+				continue
+			}
+			diff.DroppedColumns = append(diff.DroppedColumns, cur.Fields[i])
+		}
+	}
+
+	for i := range sc.Fields {
+		field := &sc.Fields[i]
+		want := *field
+		want.Type = d.ColumnType(field)
+		if td, ok := d.(timestampDefaulter); ok && want.DefaultValue == "" {
+			want.DefaultValue = td.timestampDefault(field)
+		}
+		if from, ok := renamedFrom[field.Name]; ok {
+			change := ColumnChange{Old: *cur.Field(from), New: *field, TypeChange: classifyTypeChange(cur.Field(from).Type, want.Type)}
+			if opts.SafeMode && change.TypeChange >= TypeNarrowing {
+				diff.RefusedModifications = append(diff.RefusedModifications, change)
+				continue
+			}
+			diff.RenamedColumns = append(diff.RenamedColumns, change)
+			continue
+		}
+		fd := cur.Field(field.Name)
+		if fd == nil {
+			diff.AddedColumns = append(diff.AddedColumns, *field)
+			continue
+		}
+		if !fd.Equal(&want) {
+			change := ColumnChange{Old: *fd, New: *field, TypeChange: classifyTypeChange(fd.Type, want.Type)}
+			if opts.SafeMode && change.TypeChange >= TypeNarrowing {
+				diff.RefusedModifications = append(diff.RefusedModifications, change)
+				continue
+			}
+			diff.ModifiedColumns = append(diff.ModifiedColumns, change)
+		}
+	}
+
+	for i := range cur.Indices {
+		name := cur.Indices[i].Name
+		if cur.Indices[i].Primary {
+			name = "PRIMARY"
+		}
+		// MySQL creates an index named after the foreign key constraint, it's dropped with the constraint
+		if sc.Index(name) == nil && sc.ForeignKey(name) == nil {
+			if opts.SafeMode {
+				diff.RefusedIndices = append(diff.RefusedIndices, cur.Indices[i])
+				continue
+			}
+			diff.DroppedIndices = append(diff.DroppedIndices, cur.Indices[i])
+		}
+	}
+
+	for i := range sc.Indices {
+		index := &sc.Indices[i]
+		name := index.Name
+		if index.Primary {
+			name = "PRIMARY"
+		}
+		idx := cur.Index(name)
+		if idx == nil {
+			diff.AddedIndices = append(diff.AddedIndices, *index)
+			continue
+		}
+		// The indices follow the renamed columns
+		renamedIdx := *idx
+		renamedIdx.Columns = renameColumns(idx.Columns)
+		if !renamedIdx.Equal(index) {
+			diff.ModifiedIndices = append(diff.ModifiedIndices, IndexChange{Old: *idx, New: *index})
+		}
+	}
+
+	for i := range sc.ForeignKeys {
+		fk := cur.ForeignKey(sc.ForeignKeyName(&sc.ForeignKeys[i]))
+		if fk == nil || !fk.Equal(&sc.ForeignKeys[i]) {
+			diff.AddedForeignKeys = append(diff.AddedForeignKeys, sc.ForeignKeys[i])
+		}
+	}
+
+	return diff, nil
+}
+
+// Statements returns the statements to apply the changes, the table is created if it does not exist.
+// Foreign keys and checks are dropped before the columns they reference and added after all other changes.
+// As the changes are computed from the current schema, running the statements of a new diff after a
+// partial failure converges instead of failing on the indices already added or dropped.
+func (diff *SchemaDiff) Statements() ([]string, error) {
+	d, sc, cur := diff.dialect, diff.Schema, diff.Current
+	if d == nil {
+		d = sc.dialect()
+	}
+	if cur == nil {
+		return d.CreateTable(sc)
+	}
+
+	stmts := make([]string, 0, 8)
+	add := func(s []string, e error) error {
+		stmts = append(stmts, s...)
+		return e
+	}
+
+	if diff.TableOptionsChanged {
+		if e := add(d.AlterTable(sc, cur)); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.DroppedForeignKeys {
+		if e := add(d.DropForeignKey(cur, &diff.DroppedForeignKeys[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	// The checks are dropped before the columns they refer to
+	for i := range diff.DroppedChecks {
+		if e := add(d.DropCheck(cur, &diff.DroppedChecks[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	// The indices are dropped before their columns, the server drops an index with its last column and a
+	// later DROP INDEX would fail
+	for i := range diff.DroppedIndices {
+		if e := add(d.DropIndex(sc, &diff.DroppedIndices[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.DroppedColumns {
+		if e := add(d.DropColumn(sc, &diff.DroppedColumns[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.RenamedColumns {
+		if e := add(d.RenameColumn(sc, &diff.RenamedColumns[i].New, &diff.RenamedColumns[i].Old)); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.ModifiedColumns {
+		if e := add(d.ModifyColumn(sc, &diff.ModifiedColumns[i].New, &diff.ModifiedColumns[i].Old)); e != nil {
+			return nil, e
+		}
+	}
+
+	// The preceding column of an added one doesn't exist if its rename or modification was refused, the added
+	// column is positioned only after an existing column or one added before it
+	present := make(map[string]bool)
+	for i := range cur.Fields {
+		present[cur.Fields[i].Name] = true
+	}
+	for i := range diff.DroppedColumns {
+		delete(present, diff.DroppedColumns[i].Name)
+	}
+	for i := range diff.RenamedColumns {
+		delete(present, diff.RenamedColumns[i].Old.Name)
+		present[diff.RenamedColumns[i].New.Name] = true
+	}
+	for i := range diff.AddedColumns {
+		field := &diff.AddedColumns[i]
+		placed := *sc
+		placed.Fields = nil
+		for j := range sc.Fields {
+			if sc.Fields[j].Name == field.Name {
+				if j == 0 || present[sc.Fields[j-1].Name] {
+					placed.Fields = sc.Fields
+				}
+				break
+			}
+		}
+		if e := add(d.AddColumn(&placed, field)); e != nil {
+			return nil, e
+		}
+		present[field.Name] = true
+	}
+
+	for i := range diff.ModifiedIndices {
+		if e := add(d.ModifyIndex(sc, &diff.ModifiedIndices[i].New, &diff.ModifiedIndices[i].Old)); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.AddedIndices {
+		if e := add(d.AddIndex(sc, &diff.AddedIndices[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.AddedForeignKeys {
+		if e := add(d.AddForeignKey(sc, &diff.AddedForeignKeys[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	for i := range diff.AddedChecks {
+		if e := add(d.AddCheck(sc, &diff.AddedChecks[i])); e != nil {
+			return nil, e
+		}
+	}
+
+	return stmts, nil
+}
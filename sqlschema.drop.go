@@ -0,0 +1,35 @@
+package sqlschema
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Drop drops the table if it exists
+func (sc *Schema) Drop(db Executor, ctx context.Context) error {
+	if _, e := execLogged(ctx, db.ExecContext, sc.DropSQL()); e != nil {
+		return errors.Wrap(e, "Drop table failed")
+	}
+	return nil
+}
+
+// DropSQL returns the statement Drop would execute
+func (sc *Schema) DropSQL() string {
+	return "DROP TABLE IF EXISTS " + quoteSchemaTable(sc.dialect(), sc.Name)
+}
+
+// DropColumn drops the column from the table, the column is dropped from the database only, a field
+// with the name in sc.Fields would be added back by the next Update.
+func (sc *Schema) DropColumn(db Executor, ctx context.Context, name string) error {
+	stmts, e := sc.dialect().DropColumn(sc, &Field{Name: name})
+	if e != nil {
+		return e
+	}
+	for _, stmt := range stmts {
+		if _, e := execLogged(ctx, db.ExecContext, stmt); e != nil {
+			return errors.Wrapf(e, "Drop column %s failed", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The values of an enum declared by enum(<name>[=<value>],...), a name without a value follows the previous one
+// from 0 like iota, e.g. enum(active=1,inactive,banned) is active=1, inactive=2 and banned=3
+type enumValues struct {
+	names  map[int64]string
+	values map[string]int64
+}
+
+func parseEnum(param string) *enumValues {
+	enum := &enumValues{names: make(map[int64]string), values: make(map[string]int64)}
+	next := int64(0)
+	for _, item := range strings.Split(param, ",") {
+		name := strings.TrimSpace(item)
+		if i := strings.Index(name, "="); i >= 0 {
+			if n, e := strconv.ParseInt(strings.TrimSpace(name[i+1:]), 10, 64); e == nil {
+				next = n
+			}
+			name = strings.TrimSpace(name[:i])
+		}
+		if name == "" {
+			continue
+		}
+		enum.names[next] = name
+		enum.values[name] = next
+		next++
+	}
+	return enum
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// Encode the integer field as the name of its value, or as the integer if the column is numeric
+func (field *dataSchemaField) encodeEnum(v reflect.Value) (any, error) {
+	var n int64
+	if v.CanInt() {
+		n = v.Int()
+	} else {
+		n = int64(v.Uint())
+	}
+	name, ok := field.enum.names[n]
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidEnum, "Value %d of column %s", n, field.ColumnName)
+	}
+	if field.enumAsInt {
+		return n, nil
+	}
+	return name, nil
+}
+
+// Decode the name or the integer read from the column into the integer field
+func (field *dataSchemaField) decodeEnum(v reflect.Value, data string) error {
+	var n int64
+	if field.enumAsInt {
+		var e error
+		if n, e = strconv.ParseInt(data, 10, 64); e != nil {
+			return errors.Wrapf(e, "Deserialize column %s failed", field.ColumnName)
+		}
+		if _, ok := field.enum.names[n]; !ok {
+			return errors.Wrapf(ErrInvalidEnum, "Value %d of column %s", n, field.ColumnName)
+		}
+	} else {
+		var ok bool
+		if n, ok = field.enum.values[data]; !ok {
+			return errors.Wrapf(ErrInvalidEnum, "Value %s of column %s", data, field.ColumnName)
+		}
+	}
+	if v.CanInt() {
+		v.SetInt(n)
+	} else {
+		v.SetUint(uint64(n))
+	}
+	return nil
+}
@@ -3,5 +3,16 @@ package sqlschema
 import "errors"
 
 var (
-	ErrUnknownColumn = errors.New("unknown column")
+	ErrUnknownColumn     = errors.New("unknown column")
+	ErrNotSupported      = errors.New("not supported by the dialect")
+	ErrNoPrimaryKey      = errors.New("no primary key")
+	ErrNoRowsAffected    = errors.New("no rows affected")
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	ErrVersionConflict   = errors.New("version conflict")
+	ErrNotAStruct        = errors.New("not a struct")
+	ErrNilPointer        = errors.New("nil pointer")
+	ErrEmptyCondition    = errors.New("empty where condition")
+	ErrInvalidEnum       = errors.New("invalid enum value")
+	ErrUnexportedField   = errors.New("unexported field")
+	ErrNotAddressable    = errors.New("not addressable")
 )
@@ -0,0 +1,229 @@
+package sqlschema
+
+import (
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Common initialisms kept upper cased in the field names
+var goInitialisms = map[string]bool{
+	"id": true, "ip": true, "url": true, "uri": true, "uuid": true, "api": true,
+	"http": true, "json": true, "sql": true, "html": true, "xml": true, "utc": true,
+}
+
+// Convert a column name like user_id to an exported field name like UserID
+func goFieldName(column string) string {
+	name := ""
+	for _, part := range strings.FieldsFunc(column, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if goInitialisms[strings.ToLower(part)] {
+			name += strings.ToUpper(part)
+		} else {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	if name == "" || !(name[0] >= 'A' && name[0] <= 'Z') {
+		name = "F" + name
+	}
+	return name
+}
+
+// The Go type of a column, nullable columns are pointers
+func goFieldType(field *Field) string {
+	name, param, modifiers := splitColumnType(field.Type)
+	unsigned := strings.Contains(strings.ToLower(modifiers), "unsigned")
+	typ := "string"
+	switch name {
+	case "tinyint":
+		if param == "1" {
+			typ = "bool"
+		} else if unsigned {
+			typ = "uint8"
+		} else {
+			typ = "int8"
+		}
+	case "bit":
+		if param == "" || param == "1" {
+			typ = "bool"
+		} else {
+			typ = "uint64"
+		}
+	case "boolean", "bool":
+		typ = "bool"
+	case "smallint", "mediumint", "int", "integer":
+		if unsigned {
+			typ = "uint32"
+		} else {
+			typ = "int32"
+		}
+	case "bigint":
+		if unsigned {
+			typ = "uint64"
+		} else {
+			typ = "int64"
+		}
+	case "float", "real":
+		typ = "float32"
+	case "double":
+		typ = "float64"
+	case "tinyblob", "blob", "mediumblob", "longblob", "binary", "varbinary",
+		"point", "linestring", "polygon", "geometry", "multipoint", "multilinestring", "multipolygon", "geometrycollection":
+		return "[]byte"
+	case "date", "datetime", "timestamp":
+		typ = "time.Time"
+	}
+	if field.Nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// Escape the parameter of a tag option, the backslash and the closing parenthesis are escaped with a backslash
+func escapeTagParameter(p string) string {
+	p = strings.ReplaceAll(p, `\`, `\\`)
+	return strings.ReplaceAll(p, ")", `\)`)
+}
+
+// Escape the column name of a tag, the backslash, the spaces and the parentheses are escaped with a backslash
+func escapeColumnName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if strings.IndexByte(`\ ()`, name[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// GoStruct generates the source of a struct with the db tags describing the schema, so the tables of an existing
+// database could be used as models. The columns are mapped to the Go types by their types, decimal columns are
+// mapped to string, and the types without a type option are kept by the raw option. The indices which could not
+// be described by the tags, like an index on the columns already in another index, and the checks are written as
+// comments. An error is returned if a column contains back quotes, which could not be in the tag.
+func (sc *Schema) GoStruct(typeName string) (string, error) {
+	// The index option of the columns
+	indexOf := make(map[string]string)
+	comments := make([]string, 0)
+	for i := range sc.Indices {
+		index := &sc.Indices[i]
+		free := true
+		for _, column := range index.Columns {
+			name, rest := splitIndexColumn(column)
+			if _, ok := indexOf[name]; ok || strings.HasPrefix(rest, "(") {
+				free = false
+			}
+		}
+		if !free {
+			comments = append(comments, "// "+index.Name+" ("+strings.Join(index.Columns, ",")+")")
+			continue
+		}
+		for order, column := range index.Columns {
+			name, rest := splitIndexColumn(column)
+			option := ""
+			switch {
+			case index.Primary:
+				option = "pk"
+				if len(index.Columns) > 1 {
+					option += "(" + strconv.Itoa(order+1) + ")"
+				}
+			default:
+				param := index.Name
+				if strings.HasSuffix(rest, " DESC") {
+					param += ":desc"
+				}
+				if normalizeIndexType(index.Type) != "" {
+					param += "," + strings.ToLower(index.Type)
+				}
+				option = "index(" + escapeTagParameter(param) + ")"
+				if index.Unique {
+					option = "unique(" + escapeTagParameter(param) + ")"
+				}
+			}
+			// The comment of the index is kept by its first column
+			if order == 0 && index.Comment != "" {
+				option += " indexcomment(" + escapeTagParameter(index.Comment) + ")"
+			}
+			indexOf[name] = option
+		}
+	}
+
+	// The checks are kept as comments, the expressions read from the database may contain spaces
+	for i := range sc.Checks {
+		comments = append(comments, "// "+sc.CheckName(&sc.Checks[i])+" CHECK "+strings.ReplaceAll(sc.Checks[i].Expression, "\n", " "))
+	}
+
+	src := "type " + typeName + " struct {\n"
+	for i := range sc.Fields {
+		field := &sc.Fields[i]
+		options := []string{escapeColumnName(field.Name)}
+		name, param, modifiers := splitColumnType(field.Type)
+		_, known := columnTypeOf(name, param)
+		if param != "" {
+			name += "(" + strings.ReplaceAll(param, " ", "") + ")"
+		}
+		if !known {
+			// The types without a type option are kept by raw, which needs no escaping for the parentheses
+			name = "raw(" + strings.ReplaceAll(name, `\`, `\\`) + ")"
+		}
+		options = append(options, name)
+		if strings.Contains(strings.ToLower(modifiers), "unsigned") {
+			options = append(options, "unsigned")
+		}
+		if option, ok := indexOf[field.Name]; ok {
+			options = append(options, option)
+		}
+		if field.AutoIncrement {
+			options = append(options, "ai")
+		}
+		if field.Nullable {
+			options = append(options, "null")
+		}
+		if v := normalizeDefault(field.DefaultValue); v != "" {
+			options = append(options, "def("+escapeTagParameter(v)+")")
+		}
+		if field.OnUpdate != "" {
+			options = append(options, "onupdate("+escapeTagParameter(field.OnUpdate)+")")
+		}
+		for _, fk := range sc.ForeignKeys {
+			if len(fk.Columns) == 1 && fk.Columns[0] == field.Name && len(fk.RefColumns) == 1 {
+				fkParam := fk.RefTable + "." + fk.RefColumns[0]
+				onDelete, onUpdate := normalizeReferentialAction(fk.OnDelete), normalizeReferentialAction(fk.OnUpdate)
+				if onDelete != "" || onUpdate != "" {
+					fkParam += "," + strings.ReplaceAll(onDelete, " ", "_")
+				}
+				if onUpdate != "" {
+					fkParam += "," + strings.ReplaceAll(onUpdate, " ", "_")
+				}
+				options = append(options, "fk("+escapeTagParameter(fkParam)+")")
+			}
+		}
+		if field.Charset != "" {
+			options = append(options, "charset("+escapeTagParameter(field.Charset)+")")
+		}
+		if field.Collate != "" {
+			options = append(options, "collate("+escapeTagParameter(field.Collate)+")")
+		}
+		if field.Comment != "" {
+			options = append(options, "comment("+escapeTagParameter(field.Comment)+")")
+		}
+		tag := "db:" + strconv.Quote(strings.Join(options, " "))
+		if strings.Contains(tag, "`") {
+			return "", errors.Wrapf(ErrNotSupported, "Column %s", field.Name)
+		}
+		src += "\t" + goFieldName(field.Name) + " " + goFieldType(field) + " `" + tag + "`\n"
+	}
+	for _, comment := range comments {
+		src += "\t" + comment + "\n"
+	}
+	src += "}\n"
+
+	formatted, e := format.Source([]byte(src))
+	if e != nil {
+		return "", errors.Wrap(e, "Format struct failed")
+	}
+	return string(formatted), nil
+}
@@ -0,0 +1,46 @@
+package sqlschema
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// BeforeInserter is implemented by the structs preparing themselves before they are inserted, e.g. hashing a
+// password. It's called by Insert, InsertColumns, Upsert and InsertIfNotExists, an error aborts the insert.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterScanner is implemented by the structs processing themselves after a row is scanned, e.g. decrypting a
+// field. It's called by ScanRow, ScanRowLenient, Get and List, ScanRow calls it with context.Background().
+type AfterScanner interface {
+	AfterScan(ctx context.Context) error
+}
+
+// The value the hooks are called on, the struct pointed by v so the methods with a pointer receiver are found
+func hookTarget(v any, elem reflect.Value) any {
+	if elem.CanAddr() {
+		return elem.Addr().Interface()
+	}
+	return v
+}
+
+func beforeInsert(ctx context.Context, v any, elem reflect.Value) error {
+	if hook, ok := hookTarget(v, elem).(BeforeInserter); ok {
+		if e := hook.BeforeInsert(ctx); e != nil {
+			return errors.Wrap(e, "BeforeInsert failed")
+		}
+	}
+	return nil
+}
+
+func afterScan(ctx context.Context, v any, elem reflect.Value) error {
+	if hook, ok := hookTarget(v, elem).(AfterScanner); ok {
+		if e := hook.AfterScan(ctx); e != nil {
+			return errors.Wrap(e, "AfterScan failed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Logger is called after every statement executed by the CRUD functions, Create, Update and Drop, with the
+// error of the statement, nil if it succeeded. The statements are not logged if nil, which is the default.
+// It could be called concurrently.
+var Logger func(ctx context.Context, query string, args []any, err error)
+
+func logQuery(ctx context.Context, query string, args []any, err error) {
+	if Logger != nil {
+		Logger(ctx, query, args, err)
+	}
+}
+
+// Execute the statement through exec and log it
+func execLogged(ctx context.Context, exec execFunc, query string, args ...any) (sql.Result, error) {
+	r, e := exec(ctx, query, args...)
+	logQuery(ctx, query, args, e)
+	return r, e
+}
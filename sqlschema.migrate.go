@@ -0,0 +1,64 @@
+package sqlschema
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Migrate creates or updates the tables of the schemas, the tables referenced by the foreign keys of another
+// schema are migrated first, otherwise the schemas are migrated in the given order. It stops at the first error,
+// the tables migrated before it are kept.
+func Migrate(db Executor, ctx context.Context, schemas ...*Schema) error {
+	return MigrateWithOptions(db, ctx, nil, schemas...)
+}
+
+// MigrateWithOptions is Migrate with options applied to every schema, opts could be nil. With
+// DisableForeignKeyChecks the checks are disabled once for all the tables, by the dialect of the first schema.
+func MigrateWithOptions(db Executor, ctx context.Context, opts *UpdateOptions, schemas ...*Schema) error {
+	if opts != nil && opts.DisableForeignKeyChecks && len(schemas) > 0 {
+		inner := *opts
+		inner.DisableForeignKeyChecks = false
+		return withoutForeignKeyChecks(db, ctx, schemas[0].dialect(), func(db Executor) error {
+			return MigrateWithOptions(db, ctx, &inner, schemas...)
+		})
+	}
+
+	for _, sc := range sortByForeignKeys(schemas) {
+		if _, e := sc.UpdateWithOptions(db, ctx, opts); e != nil {
+			return errors.Wrapf(e, "Migrate table %s failed", sc.Name)
+		}
+	}
+	return nil
+}
+
+// Sort the schemas so that the referenced tables come first, the order is kept for the schemas without a
+// dependency between them. The schemas in a reference cycle are kept in the given order.
+func sortByForeignKeys(schemas []*Schema) []*Schema {
+	byTable := make(map[string]*Schema, len(schemas))
+	for _, sc := range schemas {
+		_, table := splitTableName(sc.Name)
+		byTable[table] = sc
+	}
+
+	sorted := make([]*Schema, 0, len(schemas))
+	visited := make(map[*Schema]bool, len(schemas))
+	var visit func(sc *Schema)
+	visit = func(sc *Schema) {
+		if visited[sc] {
+			return
+		}
+		visited[sc] = true
+		for i := range sc.ForeignKeys {
+			_, table := splitTableName(sc.ForeignKeys[i].RefTable)
+			if ref, ok := byTable[table]; ok && ref != sc {
+				visit(ref)
+			}
+		}
+		sorted = append(sorted, sc)
+	}
+	for _, sc := range schemas {
+		visit(sc)
+	}
+	return sorted
+}
@@ -0,0 +1,533 @@
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type mysqlDialect struct {
+	ansiQuotes bool // Quote the identifiers with double quotes
+}
+
+// MySQL is the dialect for MySQL and MariaDB, it's the default dialect
+var MySQL Dialect = mysqlDialect{}
+
+// MySQLANSI is the MySQL dialect quoting the identifiers with double quotes instead of back quotes, for the
+// servers running in the ANSI_QUOTES mode or generating portable SQL, e.g. DefaultDialect = MySQLANSI
+var MySQLANSI Dialect = mysqlDialect{ansiQuotes: true}
+
+func (d mysqlDialect) QuoteIdent(name string) string {
+	if d.ansiQuotes {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) QuoteString(s string) string {
+	return "'" + escape(s) + "'"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) ReturningClause(columns []string) string {
+	return ""
+}
+
+// InsertIgnore uses INSERT IGNORE, note the other errors like truncated values are turned into warnings as well
+func (mysqlDialect) InsertIgnore(query string) string {
+	return "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT")
+}
+
+func (mysqlDialect) ColumnType(field *Field) string {
+	return field.Type
+}
+
+func (mysqlDialect) AutoIncrementClause(field *Field) string {
+	return " AUTO_INCREMENT"
+}
+
+// TransactionalDDL returns false, MySQL commits the transaction implicitly before and after a DDL statement
+func (mysqlDialect) TransactionalDDL() bool {
+	return false
+}
+
+func (d mysqlDialect) UpsertClause(keys []string, columns []string) string {
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, d.QuoteIdent(column)+"=VALUES("+d.QuoteIdent(column)+")")
+	}
+	if len(sets) == 0 {
+		// Nothing to update, keep the existing row
+		sets = append(sets, d.QuoteIdent(keys[0])+"="+d.QuoteIdent(keys[0]))
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+}
+
+func (d mysqlDialect) columnDefinition(field *Field) string {
+	sql := d.QuoteIdent(field.Name) + " " + d.ColumnType(field)
+	if field.Charset != "" {
+		sql += " CHARACTER SET " + field.Charset
+	}
+	if field.Collate != "" {
+		sql += " COLLATE " + field.Collate
+	}
+	if field.Nullable {
+		sql += " NULL"
+	} else {
+		sql += " NOT NULL"
+	}
+	if field.AutoIncrement {
+		sql += d.AutoIncrementClause(field)
+	}
+	if field.DefaultValue != "" {
+		sql += " DEFAULT " + formatDefault(d, field)
+	} else if def := d.timestampDefault(field); def != "" {
+		sql += " DEFAULT " + def
+	}
+	if field.OnUpdate != "" {
+		sql += " ON UPDATE " + field.OnUpdate
+	}
+	if field.Comment != "" {
+		sql += " COMMENT " + d.QuoteString(field.Comment)
+	}
+	return sql
+}
+
+// The default of a NOT NULL timestamp or datetime without one, CURRENT_TIMESTAMP with the same fsp. MySQL rejects
+// their zero default in strict mode, and gives the first timestamp of a table DEFAULT CURRENT_TIMESTAMP ON UPDATE
+// CURRENT_TIMESTAMP without explicit_defaults_for_timestamp.
+func (d mysqlDialect) timestampDefault(field *Field) string {
+	if field.DefaultValue != "" || field.Nullable {
+		return ""
+	}
+	name, fsp, _ := splitColumnType(field.Type)
+	if name != "timestamp" && name != "datetime" {
+		return ""
+	}
+	if fsp != "" {
+		return "CURRENT_TIMESTAMP(" + fsp + ")"
+	}
+	return "CURRENT_TIMESTAMP"
+}
+
+func (d mysqlDialect) indexDefinition(index *Index) string {
+	sql := ""
+	typ := strings.ToUpper(index.Type)
+	if index.Primary {
+		sql = "PRIMARY KEY ("
+	} else if index.Unique {
+		sql = "UNIQUE KEY " + d.QuoteIdent(index.Name) + " ("
+	} else if typ == "FULLTEXT" || typ == "SPATIAL" {
+		sql = typ + " KEY " + d.QuoteIdent(index.Name) + " ("
+	} else {
+		sql = "KEY " + d.QuoteIdent(index.Name) + " ("
+	}
+	sql += quoteIndexColumns(d, index.Columns) + ")"
+	if typ == "HASH" || typ == "BTREE" {
+		sql += " USING " + typ
+	}
+	if index.Comment != "" {
+		sql += " COMMENT " + d.QuoteString(index.Comment)
+	}
+	return sql
+}
+
+func (d mysqlDialect) CreateTable(sc *Schema) ([]string, error) {
+	if e := checkNullsDistinct(sc.Indices...); e != nil {
+		return nil, e
+	}
+	sql := "CREATE TABLE IF NOT EXISTS " + quoteSchemaTable(d, sc.Name) + " ("
+	for i := range sc.Fields {
+		sql += d.columnDefinition(&sc.Fields[i]) + ","
+	}
+	for i := range sc.Indices {
+		sql += d.indexDefinition(&sc.Indices[i]) + ","
+	}
+	for i := range sc.ForeignKeys {
+		sql += foreignKeyDefinition(d, sc, &sc.ForeignKeys[i]) + ","
+	}
+	for i := range sc.Checks {
+		sql += checkDefinition(d, sc, &sc.Checks[i]) + ","
+	}
+	sql = sql[:len(sql)-1] + ")"
+	if sc.Engine != "" {
+		sql += " ENGINE=" + sc.Engine
+	}
+
+	if sc.Collate != "" {
+		sql += " COLLATE=" + sc.Collate
+	}
+
+	if sc.Comment != "" {
+		sql += " COMMENT=" + d.QuoteString(sc.Comment)
+	}
+
+	if sc.RowFormat != "" {
+		sql += " ROW_FORMAT=" + sc.RowFormat
+	}
+
+	if sc.AutoIncrement > 0 {
+		sql += " AUTO_INCREMENT=" + strconv.FormatInt(sc.AutoIncrement, 10)
+	}
+
+	if sc.Partitioning != nil {
+		sql += d.partitionClause(sc.Partitioning)
+	}
+	return []string{sql}, nil
+}
+
+func (d mysqlDialect) partitionClause(p *Partitioning) string {
+	sql := " PARTITION BY " + p.Method + " (" + p.Expression + ")"
+	if isHashPartitioning(p.Method) {
+		if p.Count > 0 {
+			sql += " PARTITIONS " + strconv.Itoa(p.Count)
+		}
+		return sql
+	}
+	if len(p.Partitions) > 0 {
+		sql += " (" + d.partitionDefinitions(p.Method, p.Partitions) + ")"
+	}
+	return sql
+}
+
+func (d mysqlDialect) partitionDefinitions(method string, partitions []Partition) string {
+	defs := make([]string, 0, len(partitions))
+	for _, part := range partitions {
+		def := "PARTITION " + d.QuoteIdent(part.Name)
+		switch method = normalizePartitionMethod(method); {
+		case method == "RANGE" && strings.EqualFold(part.Values, "MAXVALUE"):
+			def += " VALUES LESS THAN MAXVALUE"
+		case strings.HasPrefix(method, "RANGE"):
+			def += " VALUES LESS THAN (" + part.Values + ")"
+		case strings.HasPrefix(method, "LIST"):
+			def += " VALUES IN (" + part.Values + ")"
+		}
+		defs = append(defs, def)
+	}
+	return strings.Join(defs, ",")
+}
+
+// Return the partitions appended to the current ones, false if the partitioning changed otherwise
+func appendedPartitions(p *Partitioning, cur *Partitioning) ([]Partition, bool) {
+	if isHashPartitioning(p.Method) || len(p.Partitions) <= len(cur.Partitions) {
+		return nil, false
+	}
+	head := *p
+	head.Partitions = p.Partitions[:len(cur.Partitions)]
+	if !head.Equal(cur) {
+		return nil, false
+	}
+	return p.Partitions[len(cur.Partitions):], true
+}
+
+func (d mysqlDialect) AlterTable(sc *Schema, cur *Schema) ([]string, error) {
+	sql := ""
+	if sc.Engine != "" && sc.Engine != cur.Engine {
+		sql += " ENGINE = " + sc.Engine
+	}
+
+	if sc.Collate != "" && sc.Collate != cur.Collate {
+		sql += " COLLATE = " + sc.Collate
+	}
+
+	if sc.Comment != cur.Comment {
+		sql += " COMMENT = " + d.QuoteString(sc.Comment)
+	}
+
+	// The server reports the row format capitalized, e.g. Dynamic
+	if sc.RowFormat != "" && !strings.EqualFold(sc.RowFormat, cur.RowFormat) {
+		sql += " ROW_FORMAT = " + sc.RowFormat
+	}
+
+	if sc.AutoIncrement > cur.AutoIncrement {
+		sql += " AUTO_INCREMENT = " + strconv.FormatInt(sc.AutoIncrement, 10)
+	}
+
+	stmts := make([]string, 0, 2)
+	if sql != "" {
+		stmts = append(stmts, "ALTER TABLE "+quoteSchemaTable(d, sc.Name)+sql)
+	}
+
+	if p := sc.Partitioning; p != nil && (cur.Partitioning == nil || !p.Equal(cur.Partitioning)) {
+		clause := d.partitionClause(p)
+		if cur.Partitioning != nil {
+			if added, ok := appendedPartitions(p, cur.Partitioning); ok {
+				clause = " ADD PARTITION (" + d.partitionDefinitions(p.Method, added) + ")"
+			}
+		}
+		stmts = append(stmts, "ALTER TABLE "+quoteSchemaTable(d, sc.Name)+clause)
+	}
+
+	if len(stmts) == 0 {
+		return nil, nil
+	}
+	return stmts, nil
+}
+
+// AddColumn places the column after the one preceding it in sc, so the columns follow the order of the struct fields.
+// The position is left out if the column is not in sc, Update leaves it out when the preceding column doesn't exist.
+func (d mysqlDialect) AddColumn(sc *Schema, field *Field) ([]string, error) {
+	position := ""
+	for i := range sc.Fields {
+		if sc.Fields[i].Name == field.Name {
+			if i == 0 {
+				position = " FIRST"
+			} else {
+				position = " AFTER " + d.QuoteIdent(sc.Fields[i-1].Name)
+			}
+			break
+		}
+	}
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD COLUMN " + d.columnDefinition(field) + position}, nil
+}
+
+func (d mysqlDialect) ModifyColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " MODIFY " + d.columnDefinition(field)}, nil
+}
+
+func (d mysqlDialect) RenameColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " CHANGE COLUMN " + d.QuoteIdent(cur.Name) + " " + d.columnDefinition(field)}, nil
+}
+
+func (d mysqlDialect) DropColumn(sc *Schema, field *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP " + d.QuoteIdent(field.Name)}, nil
+}
+
+func (d mysqlDialect) AddIndex(sc *Schema, index *Index) ([]string, error) {
+	if e := checkNullsDistinct(*index); e != nil {
+		return nil, e
+	}
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD " + d.indexDefinition(index)}, nil
+}
+
+func (d mysqlDialect) ModifyIndex(sc *Schema, index *Index, cur *Index) ([]string, error) {
+	if e := checkNullsDistinct(*index); e != nil {
+		return nil, e
+	}
+	if index.Primary {
+		return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP PRIMARY KEY, ADD " + d.indexDefinition(index)}, nil
+	}
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP INDEX " + d.QuoteIdent(cur.Name) + ", ADD " + d.indexDefinition(index)}, nil
+}
+
+// MySQL has no DROP INDEX IF EXISTS, an index dropped before a failure is not dropped again as the statements
+// of the next Update are computed from the current schema. The drop and add of ModifyIndex are one statement.
+func (d mysqlDialect) DropIndex(sc *Schema, index *Index) ([]string, error) {
+	if index.Primary {
+		return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP PRIMARY KEY"}, nil
+	}
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP INDEX " + d.QuoteIdent(index.Name)}, nil
+}
+
+func (d mysqlDialect) AddForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD " + foreignKeyDefinition(d, sc, fk)}, nil
+}
+
+func (d mysqlDialect) DropForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP FOREIGN KEY " + d.QuoteIdent(sc.ForeignKeyName(fk))}, nil
+}
+
+func (d mysqlDialect) AddCheck(sc *Schema, ck *Check) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD " + checkDefinition(d, sc, ck)}, nil
+}
+
+func (d mysqlDialect) DropCheck(sc *Schema, ck *Check) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP CHECK " + d.QuoteIdent(sc.CheckName(ck))}, nil
+}
+
+// ForeignKeyChecks keeps the setting of the session in a user variable like mysqldump does
+func (mysqlDialect) ForeignKeyChecks() ([]string, []string, error) {
+	return []string{"SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0"}, []string{"SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS"}, nil
+}
+
+func (mysqlDialect) ReadTable(db Executor, ctx context.Context, sc *Schema) (bool, error) {
+	schema, table := splitTableName(sc.Name)
+	// AUTO_INCREMENT is NULL if the table has no auto increment column, MySQL 8 caches it for
+	// information_schema_stats_expiry seconds
+	var autoIncrement sql.NullInt64
+	var rowFormat sql.NullString
+	if e := db.QueryRowContext(ctx, "SELECT `ENGINE`,`TABLE_COLLATION`,`TABLE_COMMENT`,`ROW_FORMAT`,`AUTO_INCREMENT` FROM `information_schema`.`TABLES` WHERE `TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND `TABLE_NAME` = ?", schema, table).Scan(&sc.Engine, &sc.Collate, &sc.Comment, &rowFormat, &autoIncrement); e != nil {
+		if e == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrap(e, "Get table info failed")
+	}
+	sc.RowFormat = rowFormat.String
+	sc.AutoIncrement = autoIncrement.Int64
+	if e := readMySQLPartitions(db, ctx, sc); e != nil {
+		return false, e
+	}
+	return true, nil
+}
+
+// Read the partitioning of the table, a table without partitions has a single row with a NULL name
+func readMySQLPartitions(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT `PARTITION_NAME`,`PARTITION_METHOD`,`PARTITION_EXPRESSION`,`PARTITION_DESCRIPTION` FROM `information_schema`.`PARTITIONS` WHERE `TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND `TABLE_NAME` = ? AND `PARTITION_NAME` IS NOT NULL ORDER BY `PARTITION_ORDINAL_POSITION`,`SUBPARTITION_ORDINAL_POSITION`", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table partitions failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, method string
+		var expression, description sql.NullString
+		if e := rows.Scan(&name, &method, &expression, &description); e != nil {
+			return errors.Wrap(e, "Scan table partitions failed")
+		}
+		p := sc.Partitioning
+		if p == nil {
+			p = &Partitioning{Method: method, Expression: expression.String}
+			sc.Partitioning = p
+		}
+		// The subpartitions are listed in the rows of their partition
+		if n := len(p.Partitions); n > 0 && p.Partitions[n-1].Name == name {
+			continue
+		}
+		if isHashPartitioning(method) {
+			p.Count++
+		} else {
+			p.Partitions = append(p.Partitions, Partition{Name: name, Values: description.String})
+		}
+	}
+	return rows.Err()
+}
+
+func (mysqlDialect) ReadColumns(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT `COLUMN_NAME`,`COLUMN_TYPE`,`IS_NULLABLE`,`COLUMN_DEFAULT`,`COLUMN_COMMENT`,`EXTRA`,`CHARACTER_SET_NAME`,`COLLATION_NAME` FROM `information_schema`.`COLUMNS` WHERE `TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND `TABLE_NAME` = ? ORDER BY `ORDINAL_POSITION`", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table columns failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field Field
+		var extra, isNullable string
+		var defaultValue, charset, collate sql.NullString
+		if e := rows.Scan(&field.Name, &field.Type, &isNullable, &defaultValue, &field.Comment, &extra, &charset, &collate); e != nil {
+			return errors.Wrap(e, "Scan table columns failed")
+		}
+		extra = strings.ToLower(extra)
+		if strings.Contains(extra, "auto_increment") {
+			field.AutoIncrement = true
+		}
+		// The EXTRA is like "DEFAULT_GENERATED on update CURRENT_TIMESTAMP" on MySQL 8
+		if i := strings.Index(extra, "on update "); i >= 0 {
+			field.OnUpdate = strings.ToUpper(strings.TrimSpace(extra[i+len("on update "):]))
+		}
+		if isNullable == "YES" {
+			field.Nullable = true
+		}
+		if defaultValue.Valid {
+			field.DefaultValue = defaultValue.String
+		}
+		field.Charset = charset.String
+		field.Collate = collate.String
+		sc.Fields = append(sc.Fields, field)
+	}
+	return rows.Err()
+}
+
+func (mysqlDialect) ReadIndices(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT `INDEX_NAME`,`SEQ_IN_INDEX`,`COLUMN_NAME`,`NON_UNIQUE`,`SUB_PART`,`INDEX_TYPE`,`COLLATION`,`INDEX_COMMENT` FROM `information_schema`.`STATISTICS` WHERE `TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND `TABLE_NAME` = ? ORDER BY `INDEX_NAME`,`SEQ_IN_INDEX`", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table indices failed")
+	}
+	defer rows.Close()
+
+	idxMap := make(map[string]int)
+	for rows.Next() {
+		var idxName string
+		var idxColumn, idxType string
+		var seq, nonUnique int
+		var subPart sql.NullInt64
+		var collation, comment sql.NullString
+
+		if e := rows.Scan(&idxName, &seq, &idxColumn, &nonUnique, &subPart, &idxType, &collation, &comment); e != nil {
+			return errors.Wrap(e, "Scan table indices failed")
+		}
+		// The prefix length of the column is kept in the column like name(10)
+		if subPart.Valid {
+			idxColumn += "(" + strconv.FormatInt(subPart.Int64, 10) + ")"
+		}
+		// The collation is A for ascending, D for descending and NULL if not sorted
+		if collation.String == "D" {
+			idxColumn += " DESC"
+		}
+
+		if i, ok := idxMap[idxName]; !ok {
+			idxMap[idxName] = len(sc.Indices)
+			index := Index{Name: idxName, Columns: []string{idxColumn}, Type: idxType, Comment: comment.String}
+			if index.Name == "PRIMARY" {
+				index.Primary = true
+			} else if nonUnique == 0 {
+				index.Unique = true
+			}
+			sc.Indices = append(sc.Indices, index)
+		} else {
+			sc.Indices[i].Columns = append(sc.Indices[i].Columns, idxColumn)
+		}
+	}
+	return rows.Err()
+}
+
+func (mysqlDialect) ReadForeignKeys(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT k.`CONSTRAINT_NAME`,k.`COLUMN_NAME`,k.`TABLE_SCHEMA`,k.`REFERENCED_TABLE_SCHEMA`,k.`REFERENCED_TABLE_NAME`,k.`REFERENCED_COLUMN_NAME`,r.`DELETE_RULE`,r.`UPDATE_RULE` FROM `information_schema`.`KEY_COLUMN_USAGE` k JOIN `information_schema`.`REFERENTIAL_CONSTRAINTS` r ON r.`CONSTRAINT_SCHEMA` = k.`CONSTRAINT_SCHEMA` AND r.`CONSTRAINT_NAME` = k.`CONSTRAINT_NAME` AND r.`TABLE_NAME` = k.`TABLE_NAME` WHERE k.`TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND k.`TABLE_NAME` = ? AND k.`REFERENCED_TABLE_NAME` IS NOT NULL ORDER BY k.`CONSTRAINT_NAME`,k.`ORDINAL_POSITION`", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table foreign keys failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column, tableSchema, refSchema, refTable, refColumn, onDelete, onUpdate string
+		if e := rows.Scan(&name, &column, &tableSchema, &refSchema, &refTable, &refColumn, &onDelete, &onUpdate); e != nil {
+			return errors.Wrap(e, "Scan table foreign keys failed")
+		}
+		refTable = referencedTable(tableSchema, refSchema, refTable)
+		appendForeignKeyColumn(sc, ForeignKey{Name: name, RefTable: refTable, OnDelete: onDelete, OnUpdate: onUpdate}, column, refColumn)
+	}
+	return rows.Err()
+}
+
+// Check whether the server reports the checks, the servers before MySQL 8.0.16 parse and ignore them
+func (mysqlDialect) checksSupported(db Executor, ctx context.Context) (bool, error) {
+	var n int
+	if e := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM `information_schema`.`TABLES` WHERE `TABLE_SCHEMA` = 'information_schema' AND `TABLE_NAME` = 'CHECK_CONSTRAINTS'").Scan(&n); e != nil {
+		return false, errors.Wrap(e, "Get table checks failed")
+	}
+	return n > 0, nil
+}
+
+// ReadChecks returns ErrNotSupported before MySQL 8.0.16, the older servers parse and ignore the checks
+func (d mysqlDialect) ReadChecks(db Executor, ctx context.Context, sc *Schema) error {
+	if ok, e := d.checksSupported(db, ctx); e != nil {
+		return e
+	} else if !ok {
+		return errors.Wrap(ErrNotSupported, "Check constraints")
+	}
+
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT t.`CONSTRAINT_NAME`,c.`CHECK_CLAUSE` FROM `information_schema`.`TABLE_CONSTRAINTS` t JOIN `information_schema`.`CHECK_CONSTRAINTS` c ON c.`CONSTRAINT_SCHEMA` = t.`CONSTRAINT_SCHEMA` AND c.`CONSTRAINT_NAME` = t.`CONSTRAINT_NAME` WHERE t.`TABLE_SCHEMA` = COALESCE(NULLIF(?, ''), DATABASE()) AND t.`TABLE_NAME` = ? AND t.`CONSTRAINT_TYPE` = 'CHECK' ORDER BY t.`CONSTRAINT_NAME`", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table checks failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ck Check
+		if e := rows.Scan(&ck.Name, &ck.Expression); e != nil {
+			return errors.Wrap(e, "Scan table checks failed")
+		}
+		sc.Checks = append(sc.Checks, ck)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,539 @@
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type postgresDialect struct{}
+
+// Postgres is the dialect for PostgreSQL 10+
+var Postgres Dialect = postgresDialect{}
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgresDialect) QuoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// ReturningClause returns the generated values, PostgreSQL does not support LastInsertId
+func (d postgresDialect) ReturningClause(columns []string) string {
+	return " RETURNING " + quoteColumns(d, columns)
+}
+
+func (postgresDialect) InsertIgnore(query string) string {
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+// ColumnType maps the MySQL column types to the names reported by information_schema.columns of PostgreSQL,
+// so that a schema read from the database could be compared with the one defined in the struct.
+func (postgresDialect) ColumnType(field *Field) string {
+	name, param, _ := splitColumnType(field.Type)
+	switch name {
+	case "tinyint":
+		// tinyint(1) is the type of the bool fields
+		if param == "1" {
+			return "boolean"
+		}
+		return "smallint"
+	case "bool", "boolean":
+		return "boolean"
+	case "smallint":
+		return "smallint"
+	case "mediumint", "int", "integer":
+		return "integer"
+	case "bigint":
+		return "bigint"
+	case "float", "real":
+		return "real"
+	case "double":
+		return "double precision"
+	case "decimal", "numeric":
+		if param != "" {
+			return "numeric(" + strings.ReplaceAll(param, " ", "") + ")"
+		}
+		return "numeric"
+	case "varchar", "character varying":
+		if param != "" {
+			return "character varying(" + param + ")"
+		}
+		return "character varying"
+	case "char", "character":
+		if param != "" {
+			return "character(" + param + ")"
+		}
+		return "character"
+	case "tinytext", "text", "mediumtext", "longtext":
+		return "text"
+	case "tinyblob", "blob", "mediumblob", "longblob", "bytea":
+		return "bytea"
+	case "datetime", "timestamp":
+		// The precision defaults to 6 and is only reported by information_schema when it differs
+		typ := "timestamp"
+		if param != "" && param != "6" {
+			typ += "(" + param + ")"
+		}
+		if name == "datetime" {
+			return typ + " without time zone"
+		}
+		return typ + " with time zone"
+	}
+	return strings.ToLower(field.Type)
+}
+
+func (postgresDialect) AutoIncrementClause(field *Field) string {
+	return " GENERATED BY DEFAULT AS IDENTITY"
+}
+
+func (postgresDialect) TransactionalDDL() bool {
+	return true
+}
+
+func (d postgresDialect) UpsertClause(keys []string, columns []string) string {
+	return onConflictClause(d, keys, columns)
+}
+
+func (d postgresDialect) columnDefinition(field *Field) string {
+	sql := d.QuoteIdent(field.Name) + " " + d.ColumnType(field)
+	if field.Collate != "" {
+		sql += " COLLATE " + d.QuoteIdent(field.Collate)
+	}
+	if field.AutoIncrement {
+		sql += d.AutoIncrementClause(field)
+	}
+	if field.Nullable {
+		sql += " NULL"
+	} else {
+		sql += " NOT NULL"
+	}
+	if field.DefaultValue != "" && !field.AutoIncrement {
+		sql += " DEFAULT " + d.formatDefault(field)
+	}
+	return sql
+}
+
+// The defaults 0 and 1 of the bool fields are written as FALSE and TRUE, a boolean column refuses the integers
+func (d postgresDialect) formatDefault(field *Field) string {
+	if d.ColumnType(field) == "boolean" {
+		switch field.DefaultValue {
+		case "0":
+			return "FALSE"
+		case "1":
+			return "TRUE"
+		}
+	}
+	return formatDefault(d, field)
+}
+
+func (d postgresDialect) commentOnColumn(sc *Schema, field *Field) string {
+	comment := "NULL"
+	if field.Comment != "" {
+		comment = d.QuoteString(field.Comment)
+	}
+	return "COMMENT ON COLUMN " + quoteSchemaTable(d, sc.Name) + "." + d.QuoteIdent(field.Name) + " IS " + comment
+}
+
+func (d postgresDialect) commentOnTable(sc *Schema) string {
+	comment := "NULL"
+	if sc.Comment != "" {
+		comment = d.QuoteString(sc.Comment)
+	}
+	return "COMMENT ON TABLE " + quoteSchemaTable(d, sc.Name) + " IS " + comment
+}
+
+// The statements to create the index and set its comment
+func (d postgresDialect) createIndex(sc *Schema, index *Index) []string {
+	sql := "CREATE INDEX IF NOT EXISTS "
+	if index.Unique {
+		sql = "CREATE UNIQUE INDEX IF NOT EXISTS "
+	}
+	sql += d.QuoteIdent(index.Name) + " ON " + quoteSchemaTable(d, sc.Name)
+	if index.Type != "" {
+		sql += " USING " + strings.ToLower(index.Type)
+	}
+	sql += " (" + quoteIndexColumns(d, index.Columns) + ")"
+	if index.Unique && index.NullsNotDistinct {
+		sql += " NULLS NOT DISTINCT"
+	}
+	if index.Comment != "" {
+		return []string{sql, "COMMENT ON INDEX " + quoteQualifiedName(d, sc.Name, index.Name) + " IS " + d.QuoteString(index.Comment)}
+	}
+	return []string{sql}
+}
+
+func (d postgresDialect) CreateTable(sc *Schema) ([]string, error) {
+	if e := checkPartitioning(sc); e != nil {
+		return nil, e
+	}
+	stmts := make([]string, 0, 1)
+	sql := "CREATE TABLE IF NOT EXISTS " + quoteSchemaTable(d, sc.Name) + " ("
+	for i := range sc.Fields {
+		sql += d.columnDefinition(&sc.Fields[i]) + ","
+	}
+	for i := range sc.Indices {
+		if sc.Indices[i].Primary {
+			sql += "PRIMARY KEY (" + quoteIndexColumns(d, sc.Indices[i].Columns) + "),"
+		}
+	}
+	for i := range sc.ForeignKeys {
+		sql += foreignKeyDefinition(d, sc, &sc.ForeignKeys[i]) + ","
+	}
+	for i := range sc.Checks {
+		sql += checkDefinition(d, sc, &sc.Checks[i]) + ","
+	}
+	stmts = append(stmts, sql[:len(sql)-1]+")")
+
+	for i := range sc.Indices {
+		if !sc.Indices[i].Primary {
+			stmts = append(stmts, d.createIndex(sc, &sc.Indices[i])...)
+		}
+	}
+	if sc.Comment != "" {
+		stmts = append(stmts, d.commentOnTable(sc))
+	}
+	for i := range sc.Fields {
+		if sc.Fields[i].Comment != "" {
+			stmts = append(stmts, d.commentOnColumn(sc, &sc.Fields[i]))
+		}
+	}
+	return stmts, nil
+}
+
+// AlterTable only manages the table comment, Engine, Collate, RowFormat and AutoIncrement are MySQL specific and ignored.
+func (d postgresDialect) AlterTable(sc *Schema, cur *Schema) ([]string, error) {
+	if e := checkPartitioning(sc); e != nil {
+		return nil, e
+	}
+	if sc.Comment != cur.Comment {
+		return []string{d.commentOnTable(sc)}, nil
+	}
+	return nil, nil
+}
+
+func (d postgresDialect) AddColumn(sc *Schema, field *Field) ([]string, error) {
+	stmts := []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD COLUMN " + d.columnDefinition(field)}
+	if field.Comment != "" {
+		stmts = append(stmts, d.commentOnColumn(sc, field))
+	}
+	return stmts, nil
+}
+
+func (d postgresDialect) ModifyColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	column := d.QuoteIdent(field.Name)
+	alters := make([]string, 0, 4)
+	collateChanged := field.Collate != "" && !strings.EqualFold(field.Collate, cur.Collate)
+	resetDefault := false
+	if typ := d.ColumnType(field); typ != cur.Type || collateChanged {
+		if field.Collate != "" {
+			typ += " COLLATE " + d.QuoteIdent(field.Collate)
+		}
+		using := column + "::" + d.ColumnType(field)
+		// There is no cast from smallint to boolean, the columns created as smallint are cast through integer.
+		// The integer default could not be cast either, it's dropped and set again.
+		if d.ColumnType(field) == "boolean" && (cur.Type == "smallint" || cur.Type == "integer") {
+			using = column + "::integer::boolean"
+			if cur.DefaultValue != "" {
+				alters = append(alters, "ALTER COLUMN "+column+" DROP DEFAULT")
+				resetDefault = true
+			}
+		}
+		alters = append(alters, "ALTER COLUMN "+column+" TYPE "+typ+" USING "+using)
+	}
+	if field.Nullable != cur.Nullable {
+		if field.Nullable {
+			alters = append(alters, "ALTER COLUMN "+column+" DROP NOT NULL")
+		} else {
+			alters = append(alters, "ALTER COLUMN "+column+" SET NOT NULL")
+		}
+	}
+	if field.AutoIncrement != cur.AutoIncrement {
+		if field.AutoIncrement {
+			alters = append(alters, "ALTER COLUMN "+column+" ADD"+d.AutoIncrementClause(field))
+		} else {
+			alters = append(alters, "ALTER COLUMN "+column+" DROP IDENTITY IF EXISTS")
+		}
+	}
+	if (resetDefault || normalizeDefault(field.DefaultValue) != normalizeDefault(cur.DefaultValue)) && !field.AutoIncrement {
+		if field.DefaultValue != "" {
+			alters = append(alters, "ALTER COLUMN "+column+" SET DEFAULT "+d.formatDefault(field))
+		} else if !resetDefault {
+			alters = append(alters, "ALTER COLUMN "+column+" DROP DEFAULT")
+		}
+	}
+
+	stmts := make([]string, 0, 2)
+	if len(alters) > 0 {
+		stmts = append(stmts, "ALTER TABLE "+quoteSchemaTable(d, sc.Name)+" "+strings.Join(alters, ", "))
+	}
+	if field.Comment != cur.Comment {
+		stmts = append(stmts, d.commentOnColumn(sc, field))
+	}
+	return stmts, nil
+}
+
+func (d postgresDialect) RenameColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	stmts := []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " RENAME COLUMN " + d.QuoteIdent(cur.Name) + " TO " + d.QuoteIdent(field.Name)}
+	renamed := *cur
+	renamed.Name = field.Name
+	want := *field
+	want.Type = d.ColumnType(field)
+	if renamed.Equal(&want) {
+		return stmts, nil
+	}
+	modify, e := d.ModifyColumn(sc, field, &renamed)
+	return append(stmts, modify...), e
+}
+
+func (d postgresDialect) DropColumn(sc *Schema, field *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP COLUMN " + d.QuoteIdent(field.Name)}, nil
+}
+
+func (d postgresDialect) AddIndex(sc *Schema, index *Index) ([]string, error) {
+	if index.Primary {
+		return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD PRIMARY KEY (" + quoteIndexColumns(d, index.Columns) + ")"}, nil
+	}
+	return d.createIndex(sc, index), nil
+}
+
+func (d postgresDialect) ModifyIndex(sc *Schema, index *Index, cur *Index) ([]string, error) {
+	if index.Primary {
+		return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP CONSTRAINT " + d.QuoteIdent(cur.Name) + ", ADD PRIMARY KEY (" + quoteIndexColumns(d, index.Columns) + ")"}, nil
+	}
+	return append([]string{"DROP INDEX IF EXISTS " + quoteQualifiedName(d, sc.Name, cur.Name)}, d.createIndex(sc, index)...), nil
+}
+
+func (d postgresDialect) DropIndex(sc *Schema, index *Index) ([]string, error) {
+	if index.Primary {
+		return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP CONSTRAINT " + d.QuoteIdent(index.Name)}, nil
+	}
+	return []string{"DROP INDEX IF EXISTS " + quoteQualifiedName(d, sc.Name, index.Name)}, nil
+}
+
+func (d postgresDialect) AddForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD " + foreignKeyDefinition(d, sc, fk)}, nil
+}
+
+func (d postgresDialect) DropForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP CONSTRAINT " + d.QuoteIdent(sc.ForeignKeyName(fk))}, nil
+}
+
+func (d postgresDialect) AddCheck(sc *Schema, ck *Check) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD " + checkDefinition(d, sc, ck)}, nil
+}
+
+func (d postgresDialect) DropCheck(sc *Schema, ck *Check) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP CONSTRAINT " + d.QuoteIdent(sc.CheckName(ck))}, nil
+}
+
+// ForeignKeyChecks returns ErrNotSupported, PostgreSQL only skips the checks for the superusers by
+// session_replication_role
+func (postgresDialect) ForeignKeyChecks() ([]string, []string, error) {
+	return nil, nil, errors.Wrap(ErrNotSupported, "Disable foreign key checks")
+}
+
+func (postgresDialect) ReadTable(db Executor, ctx context.Context, sc *Schema) (bool, error) {
+	schema, table := splitTableName(sc.Name)
+	var comment sql.NullString
+	if e := db.QueryRowContext(ctx, "SELECT obj_description(c.oid, 'pg_class') FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relname = $2 AND c.relkind IN ('r', 'p')", schema, table).Scan(&comment); e != nil {
+		if e == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrap(e, "Get table info failed")
+	}
+	sc.Comment = comment.String
+	return true, nil
+}
+
+// Default values are reported as expressions like 'foo'::character varying, only the literal is kept.
+// An explicit DEFAULT NULL is reported as NULL::character varying.
+func pgDefaultValue(v string) string {
+	if strings.HasPrefix(strings.ToUpper(v), "NULL::") {
+		return "NULL"
+	}
+	if strings.HasPrefix(v, "'") {
+		if i := strings.LastIndex(v, "'::"); i > 0 {
+			v = v[:i+1]
+		}
+		if len(v) >= 2 && strings.HasSuffix(v, "'") {
+			return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+		}
+	}
+	return v
+}
+
+func (postgresDialect) ReadColumns(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT c.column_name, c.data_type, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.datetime_precision, c.is_nullable, c.column_default, c.is_identity, col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position), c.collation_name FROM information_schema.columns c WHERE c.table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND c.table_name = $2 ORDER BY c.ordinal_position", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table columns failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field Field
+		var isNullable, isIdentity string
+		var maxLength, precision, scale, fsp sql.NullInt64
+		var defaultValue, comment, collate sql.NullString
+		if e := rows.Scan(&field.Name, &field.Type, &maxLength, &precision, &scale, &fsp, &isNullable, &defaultValue, &isIdentity, &comment, &collate); e != nil {
+			return errors.Wrap(e, "Scan table columns failed")
+		}
+		switch field.Type {
+		case "character varying", "character":
+			if maxLength.Valid {
+				field.Type += fmt.Sprintf("(%d)", maxLength.Int64)
+			}
+		case "numeric":
+			if precision.Valid {
+				field.Type += fmt.Sprintf("(%d,%d)", precision.Int64, scale.Int64)
+			}
+		case "timestamp without time zone", "timestamp with time zone":
+			if fsp.Valid && fsp.Int64 != 6 {
+				field.Type = strings.Replace(field.Type, "timestamp", fmt.Sprintf("timestamp(%d)", fsp.Int64), 1)
+			}
+		}
+		if isNullable == "YES" {
+			field.Nullable = true
+		}
+		if isIdentity == "YES" {
+			field.AutoIncrement = true
+		} else if defaultValue.Valid && strings.HasPrefix(defaultValue.String, "nextval(") {
+			// serial columns
+			field.AutoIncrement = true
+		} else if defaultValue.Valid {
+			field.DefaultValue = pgDefaultValue(defaultValue.String)
+		}
+		field.Comment = comment.String
+		field.Collate = collate.String
+		sc.Fields = append(sc.Fields, field)
+	}
+	return rows.Err()
+}
+
+// Parse the index method from an index definition, e.g. BTREE
+func pgIndexMethod(def string) string {
+	start := strings.Index(def, " USING ")
+	if start < 0 {
+		return ""
+	}
+	method := def[start+len(" USING "):]
+	if i := strings.IndexAny(method, " ("); i >= 0 {
+		method = method[:i]
+	}
+	return strings.ToUpper(method)
+}
+
+// Parse the column list from an index definition like: CREATE UNIQUE INDEX name ON public.t USING btree (a, b)
+func pgIndexColumns(def string) []string {
+	columns := make([]string, 0, 1)
+	start := strings.Index(def, " USING ")
+	if start < 0 {
+		start = 0
+	}
+	open := strings.Index(def[start:], "(")
+	if open < 0 {
+		return columns
+	}
+	list := def[start+open+1:]
+	depth := 0
+	for i, c := range list {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				list = list[:i]
+				break
+			}
+			depth--
+		}
+	}
+	for _, column := range strings.Split(list, ",") {
+		column = strings.TrimSpace(column)
+		desc := strings.HasSuffix(column, " DESC")
+		column = strings.TrimSuffix(column, " DESC")
+		if strings.HasPrefix(column, `"`) && strings.HasSuffix(column, `"`) && len(column) >= 2 {
+			column = strings.ReplaceAll(column[1:len(column)-1], `""`, `"`)
+		}
+		if desc {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func (postgresDialect) ReadIndices(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT i.indexname, i.indexdef, x.indisprimary, x.indisunique, obj_description(c.oid, 'pg_class') FROM pg_indexes i JOIN pg_namespace n ON n.nspname = i.schemaname JOIN pg_class c ON c.relname = i.indexname AND c.relnamespace = n.oid JOIN pg_index x ON x.indexrelid = c.oid WHERE i.schemaname = COALESCE(NULLIF($1, ''), current_schema()) AND i.tablename = $2 ORDER BY i.indexname", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table indices failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index Index
+		var def string
+		var comment sql.NullString
+		if e := rows.Scan(&index.Name, &def, &index.Primary, &index.Unique, &comment); e != nil {
+			return errors.Wrap(e, "Scan table indices failed")
+		}
+		if index.Primary {
+			index.Unique = false
+		}
+		index.Columns = pgIndexColumns(def)
+		index.Type = pgIndexMethod(def)
+		index.NullsNotDistinct = strings.HasSuffix(def, " NULLS NOT DISTINCT")
+		index.Comment = comment.String
+		sc.Indices = append(sc.Indices, index)
+	}
+	return rows.Err()
+}
+
+func (postgresDialect) ReadForeignKeys(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT tc.constraint_name, kcu.column_name, tc.table_schema, ccu.table_schema, ccu.table_name, ccu.column_name, rc.delete_rule, rc.update_rule FROM information_schema.table_constraints tc JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name JOIN information_schema.referential_constraints rc ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name JOIN information_schema.key_column_usage ccu ON ccu.constraint_schema = rc.unique_constraint_schema AND ccu.constraint_name = rc.unique_constraint_name AND ccu.ordinal_position = kcu.position_in_unique_constraint WHERE tc.table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND tc.table_name = $2 AND tc.constraint_type = 'FOREIGN KEY' ORDER BY tc.constraint_name, kcu.ordinal_position", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table foreign keys failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column, tableSchema, refSchema, refTable, refColumn, onDelete, onUpdate string
+		if e := rows.Scan(&name, &column, &tableSchema, &refSchema, &refTable, &refColumn, &onDelete, &onUpdate); e != nil {
+			return errors.Wrap(e, "Scan table foreign keys failed")
+		}
+		refTable = referencedTable(tableSchema, refSchema, refTable)
+		appendForeignKeyColumn(sc, ForeignKey{Name: name, RefTable: refTable, OnDelete: onDelete, OnUpdate: onUpdate}, column, refColumn)
+	}
+	return rows.Err()
+}
+
+// ReadChecks reads the definitions by pg_get_constraintdef, which are like CHECK ((age >= 0))
+func (postgresDialect) ReadChecks(db Executor, ctx context.Context, sc *Schema) error {
+	schema, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, "SELECT con.conname, pg_get_constraintdef(con.oid) FROM pg_constraint con JOIN pg_class c ON c.oid = con.conrelid JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relname = $2 AND con.contype = 'c' ORDER BY con.conname", schema, table)
+	if e != nil {
+		return errors.Wrap(e, "Get table checks failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ck Check
+		if e := rows.Scan(&ck.Name, &ck.Expression); e != nil {
+			return errors.Wrap(e, "Scan table checks failed")
+		}
+		ck.Expression = strings.TrimPrefix(ck.Expression, "CHECK ")
+		sc.Checks = append(sc.Checks, ck)
+	}
+	return rows.Err()
+}
@@ -2,77 +2,62 @@ package sqlschema
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/pkg/errors"
 )
 
-func ReadFromDB(db *sql.DB, ctx context.Context, name string) (*Schema, error) {
-	var dbName string
-	if e := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); e != nil {
-		return nil, errors.Wrap(e, "Get database name failed")
-	}
+// ReadFromDB reads the schema of the table, the name could be in the form of schema.table to read a table
+// in another database than the current one of the connection
+func ReadFromDB(db Executor, ctx context.Context, name string) (*Schema, error) {
+	return ReadFromDBWithDialect(db, ctx, DefaultDialect, name)
+}
 
-	sc := &Schema{Name: name, Fields: make([]Field, 0), Indices: make([]Index, 0)}
-	if e := db.QueryRowContext(ctx, "SELECT `ENGINE`,`TABLE_COLLATION`,`TABLE_COMMENT` FROM `information_schema`.`TABLES` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?", dbName, name).Scan(&sc.Engine, &sc.Collate, &sc.Comment); e != nil {
-		if e == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, errors.Wrap(e, "Get table info failed")
+// ReadFromDBWithDialect reads the schema of the table through the given dialect, returns nil if the table does not exist
+func ReadFromDBWithDialect(db Executor, ctx context.Context, d Dialect, name string) (*Schema, error) {
+	sc := &Schema{Name: name, Fields: make([]Field, 0), Indices: make([]Index, 0), ForeignKeys: make([]ForeignKey, 0), Checks: make([]Check, 0), Dialect: d}
+	if ok, e := d.ReadTable(db, ctx, sc); e != nil || !ok {
+		return nil, e
 	}
 
-	rows, e := db.QueryContext(ctx, "SELECT `COLUMN_NAME`,`COLUMN_TYPE`,`IS_NULLABLE`,`COLUMN_DEFAULT`,`COLUMN_COMMENT`,`EXTRA` FROM `information_schema`.`COLUMNS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?", dbName, name)
-	if e != nil {
-		return nil, errors.Wrap(e, "Get table columns failed")
+	if e := d.ReadColumns(db, ctx, sc); e != nil {
+		return nil, e
 	}
 
-	for rows.Next() {
-		var field Field
-		var extra, isNullable string
-		var defaultValue sql.NullString
-		if e := rows.Scan(&field.Name, &field.Type, &isNullable, &defaultValue, &field.Comment, &extra); e != nil {
-			return nil, errors.Wrap(e, "Scan table columns failed")
-		}
-		if extra == "auto_increment" {
-			field.AutoIncrement = true
-		}
-		if isNullable == "YES" {
-			field.Nullable = true
-		}
-		if defaultValue.Valid {
-			field.DefaultValue = defaultValue.String
-		}
-		sc.Fields = append(sc.Fields, field)
+	if e := d.ReadIndices(db, ctx, sc); e != nil {
+		return nil, e
 	}
 
-	rows, e = db.QueryContext(ctx, "SELECT `INDEX_NAME`,`SEQ_IN_INDEX`,`COLUMN_NAME`,`NON_UNIQUE` FROM `information_schema`.`STATISTICS` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?", dbName, name)
-	if e != nil {
-		return nil, errors.Wrap(e, "Get table indexs failed")
+	if e := d.ReadForeignKeys(db, ctx, sc); e != nil {
+		return nil, e
 	}
 
-	idxMap := make(map[string]int)
-	for rows.Next() {
-		var idxName string
-		var idxColumn string
-		var seq, nonUnique int
+	// The checks are left alone by Update if the server does not report them
+	if e := d.ReadChecks(db, ctx, sc); errors.Is(e, ErrNotSupported) {
+		sc.checksUnknown = true
+	} else if e != nil {
+		return nil, e
+	}
 
-		if e := rows.Scan(&idxName, &seq, &idxColumn, &nonUnique); e != nil {
-			return nil, errors.Wrap(e, "Scan table indexs failed")
-		}
+	return sc, nil
+}
 
-		if i, ok := idxMap[idxName]; !ok {
-			idxMap[idxName] = len(sc.Indices)
-			index := Index{Name: idxName, Columns: []string{idxColumn}}
-			if index.Name == "PRIMARY" {
-				index.Primary = true
-			} else if nonUnique == 0 {
-				index.Unique = true
-			}
-			sc.Indices = append(sc.Indices, index)
-		} else {
-			sc.Indices[i].Columns = append(sc.Indices[i].Columns, idxColumn)
-		}
+// The referenced table of a foreign key read from the database, qualified by its schema if it's in another
+// schema than the table, as it's defined by the struct
+func referencedTable(schema string, refSchema string, refTable string) string {
+	if refSchema != "" && refSchema != schema {
+		return refSchema + "." + refTable
 	}
+	return refTable
+}
 
-	return sc, nil
+// Append a column of a foreign key read from the database, the columns of a constraint are read row by row
+func appendForeignKeyColumn(sc *Schema, fk ForeignKey, column string, refColumn string) {
+	if n := len(sc.ForeignKeys); n > 0 && sc.ForeignKeys[n-1].Name == fk.Name {
+		sc.ForeignKeys[n-1].Columns = append(sc.ForeignKeys[n-1].Columns, column)
+		sc.ForeignKeys[n-1].RefColumns = append(sc.ForeignKeys[n-1].RefColumns, refColumn)
+		return
+	}
+	fk.Columns = []string{column}
+	fk.RefColumns = []string{refColumn}
+	sc.ForeignKeys = append(sc.ForeignKeys, fk)
 }
@@ -3,36 +3,80 @@ package sqlschema
 /*
 The column information could be defined in the struct tag with the following format:
 `db:"<column_name> <column_type> [options...]"`
-The options could be a set of the following:
+The column type and the options could be given in any order, they could be a set of the following:
 
-	pk						- Primary Key
+	pk(<order>)				- Primary Key, the order is optional and sorts the columns of a composite primary key
 	ai						- Auto Increment
 	null					- Nullable
-	unsigned				- Unsigned
+	unsigned				- Unsigned, it could be given before or after the column type
+	version					- Optimistic lock version of an integer column, Update only updates the row with the same
+							  version and increases it, ErrVersionConflict is returned if the row has been changed
+	autocreate				- Time column set to the current time by Insert if it's zero, e.g. created_at
+	autoupdate				- Time column set to the current time by Insert and Update, e.g. updated_at
+	omitempty				- Insert leaves the column out if the field is zero, so the default value of the column is used
 	def(<value>)			- Default Value
+	onupdate(<expr>)		- Value set when the row is updated, e.g. onupdate(CURRENT_TIMESTAMP), MySQL only
 	arr(<delimiter>) 		- Mark the column as array with the given delimiter, the default delimiter is comma(,)
+							  The delimiter and backslash inside an item are escaped with a leading backslash
 	json					- Mark the column as json data
+	jsontype				- Mark the column as json data stored in a native json column, MySQL 5.7+ and PostgreSQL,
+							  use json with a text column type for the older servers
 	yaml					- Mark the column as yaml data
-	unique(<index_name>)	- Mark the column as a part of unique index with the given index name
-	index(<index_name>)		- Mark the column as a part of index with the given index name
+	enum(<name>[=<value>],...)
+							- Store an integer field, e.g. type Status int, as the name of its value in a varchar(64) column,
+							  or as the integer validated against the names if the column type is an integer type.
+							  A name without a value follows the previous one from 0, e.g. enum(active=1,inactive,banned),
+							  ErrInvalidEnum is returned for the values not declared
+	unique(<index_name>,<index_type>)
+							- Mark the column as a part of unique index with the given index name
+	nullsequal				- The unique index of the column treats NULL values as equal, so only one row could have NULL,
+							  PostgreSQL 15+ only. Other servers allow any number of NULL values in a unique index, a
+							  NOT NULL column with a default value, or a generated column, should be used instead
+	index(<index_name>,<index_type>)
+							- Mark the column as a part of index with the given index name, the index type is optional
+							  and could be btree, hash, fulltext or spatial, e.g. index(idx_content,fulltext)
+							  The column is sorted descending in the index if the name is followed by :desc, e.g. index(idx_time:desc)
 	comment(<comment_text>) - Append comment for the field
+	indexcomment(<text>)	- Comment of the index of the column, e.g. indexcomment(login), the comment of the first column is used
+	charset(<name>)			- Character set of the column, e.g. charset(utf8mb4), MySQL only
+	collate(<name>)			- Collation of the column, e.g. collate(utf8mb4_bin)
+	fk(<table>.<column>,<on delete>,<on update>)
+							- Foreign key referencing the column of the table, the column is optional and defaults to id,
+							  the actions are optional, e.g. fk(users.id,CASCADE,SET_NULL), underscores are replaced by spaces
+	check(<expression>)		- Check constraint named chk_<table>_<column>, e.g. check(age >= 0), append the ones on
+							  several columns to Schema.Checks instead
 
-The column_name could be omitted, if omitted, the field name will be used as column name.
+The options are separated by spaces, the spaces in the parameter of an option are kept, e.g. comment(user name), and
+a closing parenthesis in the parameter is escaped with a leading backslash. A space, a parenthesis or a backslash in
+the column name is escaped with a leading backslash as well, e.g. `db:"first\\ name"`.
+The column_name could be omitted, if omitted, the field name converted by NamingStrategy will be used as column name.
+The column name is omitted if the tag starts with a space or the first option has a parameter, e.g. `db:" text null"`
+or `db:"int(11) null"`, a tag like `db:"text"` names the column text.
 The column_type could be omitted, if omitted, the type will be determined by the field type, see below.
 Only one primary key could exist in a table, if more than one column is marked as primary key, a composite primary key will be created.
-The index_name could be omitted, if omitted, the the column name with a prefix('idx_') will be used as index name.
-If more than one column is marked as a part of the same index, a composite index will be created.
+The index_name could be omitted, if omitted, the the column name with a prefix('idx_') will be used as index name,
+the prefix could be changed by IndexPrefix and UniqueIndexPrefix.
+If more than one column is marked as a part of the same index, a composite index will be created, the columns
+follow the order of the fields, including the fields of the embedded structs at the position of the embedding.
 Only one index could be defined for a column, the `unique` and `index` option could NOT be used together.
-For compatibility reason, json column will be treated as text column in MySQL, and decode to json when query.
+For compatibility reason, json column will be treated as text column in MySQL, and decode to json when query,
+the jsontype option stores it in a native json column instead.
+The columns of an embedded struct without a db tag are hoisted into the parent struct, a column defined by the
+parent shadows the one with the same name in the embedded struct.
 
 The column type could be one of the following:
 
 	tinyint(<length>)		- Tiny Integer, the length is optional, if omitted, the default value 4 will be used
+	boolean					- Boolean, an alias of tinyint(1) in MySQL
+	bit(<length>)			- Bit field, the length is optional, if omitted, the default value 1 will be used
+							  A bool or integer field is scanned from the bytes reported by MySQL
 	int(<length>)			- Integer, the length is optional, if omitted, the default value 11 will be used
 	bigint(<length>)		- Big Integer, the length is optional, if omitted, the default value 20 will be used
 	float 					- Float
 	double					- Double
 	decimal(<l>, <d>)		- Decimal, the length(l) and decimals(d) are optional, if omitted, the default value 10 and 0 will be used
+							  A field implementing encoding.TextUnmarshaler but not sql.Scanner is scanned from the text of the
+							  value, so a decimal type could be used without rounding through float
 	varchar(<length>)		- Varchar, the length is optional, if omitted, the default value 64 will be used
 	text					- Text 64k
 	mediumtext				- Medium Text 16M
@@ -40,11 +84,21 @@ The column type could be one of the following:
 	blob					- Blob 64k
 	mediumblob				- Medium Blob 16M
 	longblob				- Long Blob 4G
-	timestamp				- Timestamp
-	datetime				- Datetime
+	timestamp(<fsp>)		- Timestamp, the fractional seconds precision(fsp) is optional, e.g. timestamp(3)
+	datetime(<fsp>)			- Datetime, the fractional seconds precision(fsp) is optional, e.g. datetime(6)
+							  On MySQL, a NOT NULL timestamp or datetime without def() defaults to CURRENT_TIMESTAMP
+							  with the same fsp, so the existing NOT NULL columns created without a default are
+							  modified to have it by the next Update
+	raw(<type>)				- Column type used verbatim for the types not listed here, e.g. raw(year), raw(enum('a','b')),
+							  the type could not contain spaces, the unsigned option is still applied
+	point, linestring, polygon, geometry, multipoint, multilinestring, multipolygon, geometrycollection
+							- Spatial types of MySQL, the value is passed as is, e.g. a []byte of the internal format or
+							  a type implementing driver.Valuer and sql.Scanner. A column in a spatial index must be NOT NULL.
+							  PostgreSQL only has point and polygon without PostGIS, SQLite stores them as blob
 
 The column type could be omitted, if omitted, the type will be determined by the field type in the struct with the following rules:
 
+	bool									- tinyint(1), scanned back from 0 and 1
 	int8, int16, int32						- int(11)
 	int, int64,								- bigint(20)
 	uint8, uint16, uint32					- int(11) with `unsigned` option
@@ -52,19 +106,32 @@ The column type could be omitted, if omitted, the type will be determined by the
 	float32									- float
 	float64									- double
 	string									- varchar(64)
-	[]byte									- blob
+	time.Time								- datetime, stored and scanned as native time value
+	driver.Valuer and sql.Scanner			- Passed to the driver as is, the column type is determined by the kind of the type,
+											  varchar(64) for a struct, array or map, which should be given in the tag instead
+	*<type>									- Nullable column of <type>, nil is stored as NULL
+	sql.NullString, sql.NullInt64, ...		- Nullable column of the underlying type, passed to the driver as is
+	[]byte									- blob, a named byte slice like json.RawMessage is stored as is as well
 	[]<type>								- Array of <type>, the <type> could be int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint, float32, float64 and string
 											  The array will be encoded to string and stored as mediumtext in database
 	other									- Serialized to json and stored as mediumtext in database
+
+A named type like `type UserID int64` or `type Money float64` is mapped by its underlying kind as above.
 */
 
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding"
 	"encoding/json"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -78,6 +145,9 @@ const (
 	ARRAY = 1
 	JSON  = 2
 	YAML  = 3
+	TEXT  = 4 // encoding.TextMarshaler and encoding.TextUnmarshaler, used for the decimal columns
+	BIT   = 5 // The big endian bytes of a MySQL bit column, used for the bool and integer fields
+	ENUM  = 6 // The name of the value of an integer enum, or the integer validated against the names
 
 	// Index Types
 	INDEX       = 1
@@ -88,28 +158,140 @@ const (
 type dataSchemaField struct {
 	Name               string       // Name of the field in struct
 	FieldType          reflect.Kind // Type of the field
-	FieldIndex         int
-	ColumnName         string // Name of the column in database
-	IsPrimaryKey       bool   // pk
-	IsAutoincrement    bool   // ai
-	IsNullable         bool   // null
-	DataStoreType      string // column_type
-	DefaultValue       string // def()
-	SerializeMethod    uint8  // arr | json | yaml
-	SerializeDelimiter string // delimiter
-	IndexType          uint8  // pk | index | unique
-	indexName          string // index name
-	Comment            string // comment()
+	FieldIndex         []int        // Index path of the field, fields of embedded structs have more than one
+	ColumnName         string       // Name of the column in database
+	IsPrimaryKey       bool         // pk
+	IsAutoincrement    bool         // ai
+	IsNullable         bool         // null
+	IsVersion          bool         // version
+	IsAutoCreate       bool         // autocreate
+	IsAutoUpdate       bool         // autoupdate
+	IsOmitEmpty        bool         // omitempty
+	isUnsigned         bool         // unsigned, applied after the column type is resolved
+	DataStoreType      string       // column_type
+	DefaultValue       string       // def()
+	OnUpdate           string       // onupdate()
+	Charset            string       // charset()
+	Collate            string       // collate()
+	SerializeMethod    uint8        // arr | json | yaml
+	SerializeDelimiter string       // delimiter
+	IndexType          uint8        // pk | index | unique
+	indexName          string       // index name
+	indexMethod        string       // index type, BTREE | HASH | FULLTEXT | SPATIAL
+	indexDesc          bool         // the column is sorted descending in the index
+	indexNullsEqual    bool         // nullsequal
+	indexComment       string       // indexcomment()
+	keyOrder           int          // pk(<order>)
+	ForeignKey         *ForeignKey  // fk()
+	check              string       // check()
+	enum               *enumValues  // enum()
+	enumAsInt          bool         // the enum is stored as the integer
+	Comment            string       // comment()
 }
 
 type dataSchemaInfo struct {
-	Fields      []*dataSchemaField
-	ByColumName map[string]*dataSchemaField
-	AIField     *dataSchemaField
+	Fields           []*dataSchemaField
+	ByColumName      map[string]*dataSchemaField
+	AIField          *dataSchemaField
+	VersionField     *dataSchemaField
+	AutoCreateFields []*dataSchemaField
+	AutoUpdateFields []*dataSchemaField
+	err              error // The struct could not be mapped, e.g. an unexported field has a db tag
 }
 
 var dataSchemaCache = sync.Map{}
 
+// NamingStrategy converts the field name to the column name when the column name is omitted in the tag,
+// the field name is used as is if nil. It should be set before any struct is used, e.g. NamingStrategy = SnakeCase
+var NamingStrategy func(fieldName string) string
+
+// IndexPrefix and UniqueIndexPrefix are prepended to the column name as the name of an index when the index name
+// is omitted in the tag, e.g. UniqueIndexPrefix = "uk_". They should be set before any struct is used as well.
+var (
+	IndexPrefix       = "idx_"
+	UniqueIndexPrefix = "idx_"
+)
+
+// SnakeCase converts a CamelCase name to snake_case, the initialisms are kept together, e.g. UserID to user_id
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) && runes[i-1] != '_' {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// The sql.Null* types are passed to the driver as is, they implement driver.Valuer and sql.Scanner
+var nullTypes = map[reflect.Type]string{
+	reflect.TypeOf(sql.NullString{}):  "varchar(64)",
+	reflect.TypeOf(sql.NullInt64{}):   "bigint(20)",
+	reflect.TypeOf(sql.NullInt32{}):   "int(11)",
+	reflect.TypeOf(sql.NullInt16{}):   "int(11)",
+	reflect.TypeOf(sql.NullByte{}):    "int(11) unsigned",
+	reflect.TypeOf(sql.NullFloat64{}): "double",
+	reflect.TypeOf(sql.NullBool{}):    "tinyint(1)",
+	reflect.TypeOf(sql.NullTime{}):    "datetime",
+}
+
+var (
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType          = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+var boolType = reflect.TypeOf(false)
+
+// []byte or a named type of it like json.RawMessage, it's stored as is
+func isBytesType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// A type encoding itself for the driver, it's passed to the driver as is
+func isValuerType(t reflect.Type) bool {
+	return t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType)
+}
+
+func isDecimalType(t string) bool {
+	name, _, _ := splitColumnType(t)
+	return name == "decimal" || name == "numeric"
+}
+
+// A type decoded from its text representation, the types implementing sql.Scanner scan themselves
+func isTextType(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return !pt.Implements(scannerType) && pt.Implements(textUnmarshalerType)
+}
+
+// time.Time is a struct, it must be detected before the kind of the field
+func isTimeType(t reflect.Type) bool {
+	return t == timeType
+}
+
+// The primary key columns ordered by the pk(<order>) hints, columns without a hint follow in field order
+func (info *dataSchemaInfo) primaryKeys() []*dataSchemaField {
+	pks := make([]*dataSchemaField, 0, 4)
+	for _, field := range info.Fields {
+		if field.IsPrimaryKey {
+			pks = append(pks, field)
+		}
+	}
+	sort.SliceStable(pks, func(i, j int) bool {
+		return pks[j].keyOrder == 0 && pks[i].keyOrder != 0 || pks[i].keyOrder != 0 && pks[i].keyOrder < pks[j].keyOrder
+	})
+	return pks
+}
+
 func escapeOptionParameter(p string) string {
 	s := []byte(p)
 	d := make([]byte, len(s))
@@ -138,161 +320,479 @@ func parseOption(option string) (string, string) {
 	return option[:eox], escapeOptionParameter((option[eox+1:]))
 }
 
+// Parse the parameter of fk option: <table>.<column>[,<on delete>[,<on update>]]
+func parseForeignKey(param string) *ForeignKey {
+	parts := strings.Split(param, ",")
+	fk := &ForeignKey{RefTable: parts[0], RefColumns: []string{"id"}}
+	if i := strings.LastIndex(parts[0], "."); i >= 0 {
+		fk.RefTable = parts[0][:i]
+		fk.RefColumns = []string{parts[0][i+1:]}
+	}
+	if len(parts) > 1 {
+		fk.OnDelete = strings.ToUpper(strings.ReplaceAll(parts[1], "_", " "))
+	}
+	if len(parts) > 2 {
+		fk.OnUpdate = strings.ToUpper(strings.ReplaceAll(parts[2], "_", " "))
+	}
+	return fk
+}
+
+// Parse the parameter of index and unique option: <index_name>[:desc][,<index_type>]
+func parseIndexParam(param string) (string, string, bool) {
+	name, method := param, ""
+	if i := strings.Index(param, ","); i >= 0 {
+		name, method = param[:i], strings.ToUpper(param[i+1:])
+	}
+	desc := false
+	if i := strings.Index(name, ":"); i >= 0 {
+		desc = strings.EqualFold(name[i+1:], "desc")
+		name = name[:i]
+	}
+	return name, method, desc
+}
+
+// The column type options taking a parameter and the default parameters, an empty default means the
+// parameter is optional
+var paramColumnTypes = map[string]string{
+	"tinyint":   "4",
+	"bit":       "1",
+	"int":       "11",
+	"bigint":    "20",
+	"decimal":   "10,0",
+	"varchar":   "64",
+	"timestamp": "",
+	"datetime":  "",
+}
+
+// The column type options without a parameter
+var plainColumnTypes = map[string]bool{
+	"boolean": true, "float": true, "double": true, "text": true, "mediumtext": true, "longtext": true,
+	"blob": true, "mediumblob": true, "longblob": true, "point": true, "linestring": true, "polygon": true,
+	"geometry": true, "multipoint": true, "multilinestring": true, "multipolygon": true, "geometrycollection": true,
+}
+
+// The column type of a type option, returns false if the option is not a column type
+func columnTypeOf(option string, param string) (string, bool) {
+	if option == "bool" {
+		option = "boolean"
+	}
+	if plainColumnTypes[option] {
+		return option, true
+	}
+	def, ok := paramColumnTypes[option]
+	if !ok {
+		return "", false
+	}
+	if param == "" {
+		param = def
+	}
+	if param == "" {
+		return option, true
+	}
+	return option + "(" + param + ")", true
+}
+
+// Split the tag into the options by the spaces, the spaces in the parameter of an option are kept, e.g.
+// comment(user name). The parameter ends at the first closing parenthesis without a leading backslash, the
+// escapes are kept to be removed by parseOption.
+func splitTagOptions(tag string) []string {
+	options := make([]string, 0, 4)
+	start, param := 0, false
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '\\':
+			i++
+		case '(':
+			param = true
+		case ')':
+			param = false
+		case ' ':
+			if !param {
+				options = append(options, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(options, tag[start:])
+}
+
+// Unescape the column name of a tag, a space, a parenthesis or a backslash in the name is escaped with a leading
+// backslash. It returns false if the option has a parameter and is not a column name.
+func unescapeColumnName(p string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+1 < len(p) {
+			i++
+		} else if p[i] == '(' {
+			return "", false
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String(), true
+}
+
+// Unescape the parameter of the raw option, which is the text up to the last closing parenthesis of the option,
+// so the parentheses of the type need no escaping. A character with a leading backslash is kept as is.
+func unescapeRawType(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+1 < len(p) {
+			i++
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// Parse the tag in two passes, the options are collected first and applied in a fixed order, so the order of
+// the options in the tag never changes the result. The column type given by a type option wins over the one
+// implied by the other options, like jsontype, and the unsigned modifier is applied after the type is resolved.
 func parseFieldTag(field *dataSchemaField, tag string) {
-	parts := strings.Split(tag, " ")
-	for _, p := range parts {
+	options := make(map[string]string)
+	columnType, rawType := "", ""
+	// The column name is omitted if the tag starts with a space or the first token has a parameter
+	named := strings.HasPrefix(tag, " ")
+	for _, p := range splitTagOptions(tag) {
 		if p == "" {
 			continue
 		}
-		if field.ColumnName == "" {
-			field.ColumnName = p
+		if !named {
+			named = true
+			if name, ok := unescapeColumnName(p); ok {
+				field.ColumnName = name
+				continue
+			}
+		}
+		if strings.HasPrefix(p, "raw(") && strings.HasSuffix(p, ")") {
+			rawType = unescapeRawType(p[len("raw(") : len(p)-1])
 			continue
 		}
 		option, param := parseOption(p)
-		switch option {
-		case "pk":
-			field.IsPrimaryKey = true
-			field.IndexType = PRIMARY_KEY
-			field.indexName = "PRIMARY"
-		case "ai":
-			field.IsAutoincrement = true
-		case "null":
-			field.IsNullable = true
-		case "unsigned":
-			field.DataStoreType += " unsigned"
-		case "def":
-			field.DefaultValue = param
-		case "arr":
-			field.SerializeMethod = ARRAY
-			field.SerializeDelimiter = param
-		case "json":
-			field.SerializeMethod = JSON
-		case "yaml":
-			field.SerializeMethod = YAML
-		case "unique":
-			field.IndexType = UNIQUE
-			field.indexName = param
-		case "index":
-			field.IndexType = INDEX
-			field.indexName = param
-		case "comment":
-			field.Comment = param
-		case "tinyint":
-			field.DataStoreType = "tinyint"
-			if param != "" {
-				field.DataStoreType += "(" + param + ")"
-			} else {
-				field.DataStoreType += "(4)"
-			}
-		case "int":
-			field.DataStoreType = "int"
-			if param != "" {
-				field.DataStoreType += "(" + param + ")"
-			} else {
-				field.DataStoreType += "(11)"
-			}
-		case "bigint":
-			field.DataStoreType = "bigint"
-			if param != "" {
-				field.DataStoreType += "(" + param + ")"
-			} else {
-				field.DataStoreType += "(20)"
-			}
-		case "float":
-			field.DataStoreType = "float"
-		case "double":
-			field.DataStoreType = "double"
-		case "decimal":
-			field.DataStoreType = "decimal"
-			if param != "" {
-				field.DataStoreType += "(" + param + ")"
-			} else {
-				field.DataStoreType += "(10,0)"
-			}
-		case "varchar":
-			field.DataStoreType = "varchar"
-			if param != "" {
-				field.DataStoreType += "(" + param + ")"
+		if t, ok := columnTypeOf(option, param); ok {
+			columnType = t
+			continue
+		}
+		options[option] = param
+	}
+	has := func(option string) bool {
+		_, ok := options[option]
+		return ok
+	}
+
+	// The primary key wins over the other indices
+	if param, ok := options["index"]; ok {
+		field.IndexType = INDEX
+		field.indexName, field.indexMethod, field.indexDesc = parseIndexParam(param)
+	}
+	if param, ok := options["unique"]; ok {
+		field.IndexType = UNIQUE
+		field.indexName, field.indexMethod, field.indexDesc = parseIndexParam(param)
+	}
+	if param, ok := options["pk"]; ok {
+		field.keyOrder, _ = strconv.Atoi(param)
+		field.IsPrimaryKey = true
+		field.IndexType = PRIMARY_KEY
+		field.indexName = "PRIMARY"
+	}
+	field.indexNullsEqual = has("nullsequal")
+	field.indexComment = options["indexcomment"]
+
+	field.IsAutoincrement = has("ai")
+	field.IsNullable = has("null")
+	field.IsVersion = has("version")
+	field.IsAutoCreate = has("autocreate")
+	field.IsAutoUpdate = has("autoupdate")
+	field.IsOmitEmpty = has("omitempty")
+	field.isUnsigned = has("unsigned")
+	field.DefaultValue = options["def"]
+	field.OnUpdate = options["onupdate"]
+	field.Comment = options["comment"]
+	field.Charset = options["charset"]
+	field.Collate = options["collate"]
+	field.check = options["check"]
+	if param, ok := options["fk"]; ok {
+		field.ForeignKey = parseForeignKey(param)
+	}
+
+	switch {
+	case has("arr"):
+		field.SerializeMethod = ARRAY
+		field.SerializeDelimiter = options["arr"]
+		if field.SerializeDelimiter == "" {
+			field.SerializeDelimiter = ","
+		}
+	case has("jsontype"):
+		field.SerializeMethod = JSON
+		field.DataStoreType = "json"
+	case has("json"):
+		field.SerializeMethod = JSON
+	case has("yaml"):
+		field.SerializeMethod = YAML
+	case has("enum"):
+		field.SerializeMethod = ENUM
+		field.enum = parseEnum(options["enum"])
+	}
+
+	if columnType != "" {
+		field.DataStoreType = columnType
+	}
+	if rawType != "" {
+		field.DataStoreType = rawType
+	}
+
+	if field.IndexType != NONE && field.indexName == "" {
+		if field.IndexType == UNIQUE {
+			field.indexName = UniqueIndexPrefix + field.Name
+		} else {
+			field.indexName = IndexPrefix + field.Name
+		}
+	}
+}
+
+// Create the field info from the struct field and its db tag
+func newDataSchemaField(field reflect.StructField, tag string, index []int) *dataSchemaField {
+	info := &dataSchemaField{
+		Name:       field.Name,
+		FieldType:  field.Type.Kind(),
+		FieldIndex: index,
+	}
+	parseFieldTag(info, tag)
+	if info.ColumnName == "" {
+		info.ColumnName = field.Name
+		if NamingStrategy != nil {
+			info.ColumnName = NamingStrategy(field.Name)
+		}
+	}
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		// Pointer fields are nullable, the column type is determined by the element type
+		fieldType = fieldType.Elem()
+		info.IsNullable = true
+	}
+	if storeType, ok := nullTypes[fieldType]; ok {
+		info.IsNullable = true
+		if info.DataStoreType == "" {
+			info.DataStoreType = storeType
+		}
+	}
+	if info.DataStoreType == "" && isTimeType(fieldType) {
+		info.DataStoreType = "datetime"
+	}
+	if !isTimeType(fieldType) {
+		// Only the time fields could be set to the current time
+		info.IsAutoCreate = false
+		info.IsAutoUpdate = false
+	}
+	if info.DataStoreType == "" && isValuerType(fieldType) {
+		switch fieldType.Kind() {
+		case reflect.Struct, reflect.Array, reflect.Map, reflect.Slice, reflect.Interface:
+			// The type of the value is unknown until the Value is called, the column type should be given in the tag
+			info.DataStoreType = "varchar(64)"
+		}
+	}
+	if info.SerializeMethod == ENUM {
+		if !isIntegerKind(fieldType.Kind()) {
+			// Only the integer fields are mapped
+			info.SerializeMethod = NONE
+		} else if info.DataStoreType == "" {
+			info.DataStoreType = "varchar(64)"
+		}
+		info.enumAsInt = isNumericType(info.DataStoreType)
+	}
+	if info.SerializeMethod == NONE && strings.HasPrefix(info.DataStoreType, "bit") {
+		switch fieldType.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			info.SerializeMethod = BIT
+		}
+	}
+	if info.SerializeMethod == NONE && isDecimalType(info.DataStoreType) && isTextType(fieldType) {
+		// Scanned through the text representation so that the value is never rounded by a float
+		info.SerializeMethod = TEXT
+	}
+	if info.DataStoreType == "" {
+		switch fieldType.Kind() {
+		case reflect.Bool:
+			info.DataStoreType = "tinyint(1)"
+		case reflect.Int8, reflect.Int16, reflect.Int32:
+			info.DataStoreType = "int(11)"
+		case reflect.Int, reflect.Int64:
+			info.DataStoreType = "bigint(20)"
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			info.DataStoreType = "int(11) unsigned"
+		case reflect.Uint, reflect.Uint64:
+			info.DataStoreType = "bigint(20) unsigned"
+		case reflect.Float32:
+			info.DataStoreType = "float"
+		case reflect.Float64:
+			info.DataStoreType = "double"
+		case reflect.String:
+			info.DataStoreType = "varchar(64)"
+		case reflect.Slice:
+			if isBytesType(fieldType) {
+				info.DataStoreType = "blob"
 			} else {
-				field.DataStoreType += "(64)"
+				info.DataStoreType = "mediumtext"
 			}
-		case "text":
-			field.DataStoreType = "text"
-		case "mediumtext":
-			field.DataStoreType = "mediumtext"
-		case "longtext":
-			field.DataStoreType = "longtext"
-		case "blob":
-			field.DataStoreType = "blob"
-		case "mediumblob":
-			field.DataStoreType = "mediumblob"
-		case "longblob":
-			field.DataStoreType = "longblob"
-		case "timestamp":
-			field.DataStoreType = "timestamp"
-		case "datetime":
-			field.DataStoreType = "datetime"
+		default:
+			info.DataStoreType = "int"
 		}
 	}
-	if field.IndexType != NONE && field.indexName == "" {
-		field.indexName = "idx_" + field.Name
+	if info.isUnsigned && !strings.Contains(info.DataStoreType, "unsigned") {
+		info.DataStoreType += " unsigned"
 	}
+	return info
 }
 
-func loadDataSchemaInfo(v reflect.Type) *dataSchemaInfo {
-	if pInfo, ok := dataSchemaCache.Load(v); ok {
-		return pInfo.(*dataSchemaInfo)
-	}
-	info := dataSchemaInfo{}
-	fieldCount := v.NumField()
-	info.Fields = make([]*dataSchemaField, fieldCount)
-	info.ByColumName = make(map[string]*dataSchemaField)
-	for i := 0; i < fieldCount; i++ {
+// Load the db tagged fields of the struct type v, the fields of embedded structs without a db tag
+// are hoisted into the parent. As in Go, a column of a shallower field shadows the deeper one.
+func (info *dataSchemaInfo) loadFields(v reflect.Type, index []int) {
+	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
-		if tag, ok := field.Tag.Lookup("db"); ok {
-			info.Fields[i] = &dataSchemaField{
-				Name:       field.Name,
-				FieldType:  field.Type.Kind(),
-				FieldIndex: i,
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		tag, ok := field.Tag.Lookup("db")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && !isTimeType(field.Type) {
+				info.loadFields(field.Type, fieldIndex)
 			}
-			parseFieldTag(info.Fields[i], tag)
-			if info.Fields[i].ColumnName == "" {
-				info.Fields[i].ColumnName = field.Name
+			continue
+		}
+		if !field.IsExported() {
+			// An unexported field could not be read or set by reflection
+			if info.err == nil {
+				info.err = errors.Wrapf(ErrUnexportedField, "Field %s of %s has a db tag", field.Name, v)
 			}
-			if info.Fields[i].DataStoreType == "" {
-				switch field.Type.Kind() {
-				case reflect.Int8, reflect.Int16, reflect.Int32:
-					info.Fields[i].DataStoreType = "int(11)"
-				case reflect.Int, reflect.Int64:
-					info.Fields[i].DataStoreType = "bigint(20)"
-				case reflect.Uint8, reflect.Uint16, reflect.Uint32:
-					info.Fields[i].DataStoreType = "int(11) unsigned"
-				case reflect.Uint, reflect.Uint64:
-					info.Fields[i].DataStoreType = "bigint(20) unsigned"
-				case reflect.Float32:
-					info.Fields[i].DataStoreType = "float"
-				case reflect.Float64:
-					info.Fields[i].DataStoreType = "double"
-				case reflect.String:
-					info.Fields[i].DataStoreType = "varchar(64)"
-				case reflect.Slice:
-					if field.Type.Elem().Kind() == reflect.Uint8 {
-						info.Fields[i].DataStoreType = "blob"
-					} else {
-						info.Fields[i].DataStoreType = "mediumtext"
-					}
-				default:
-					info.Fields[i].DataStoreType = "int"
+			continue
+		}
+
+		f := newDataSchemaField(field, tag, fieldIndex)
+		if existing := info.ByColumName[f.ColumnName]; existing != nil {
+			if len(existing.FieldIndex) <= len(f.FieldIndex) {
+				continue
+			}
+			for j := range info.Fields {
+				if info.Fields[j] == existing {
+					info.Fields[j] = f
 				}
 			}
-			info.ByColumName[info.Fields[i].ColumnName] = info.Fields[i]
-			if info.Fields[i].IsAutoincrement {
-				info.AIField = info.Fields[i]
+		} else {
+			info.Fields = append(info.Fields, f)
+		}
+		info.ByColumName[f.ColumnName] = f
+		if f.IsAutoincrement {
+			info.AIField = f
+		}
+		if f.IsVersion {
+			info.VersionField = f
+		}
+		if f.IsAutoCreate {
+			info.AutoCreateFields = append(info.AutoCreateFields, f)
+		}
+		if f.IsAutoUpdate {
+			info.AutoUpdateFields = append(info.AutoUpdateFields, f)
+		}
+	}
+}
+
+// ClearSchemaCache drops the parsed field info of all the struct types, they are parsed again on the next use,
+// e.g. after NamingStrategy is changed
+func ClearSchemaCache() {
+	dataSchemaCache.Range(func(key, value any) bool {
+		dataSchemaCache.Delete(key)
+		return true
+	})
+}
+
+// WarmSchemaCache parses the field info of the structs in advance, so the first query of a type doesn't pay for
+// the reflection. The values are structs or pointers to structs, the values of other types are ignored.
+func WarmSchemaCache(values ...any) {
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t != nil && t.Kind() == reflect.Struct {
+			loadDataSchemaInfo(t)
+		}
+	}
+}
+
+// An entry of the schema cache, the type is parsed once even if it's loaded by many goroutines at the same time
+type dataSchemaEntry struct {
+	once sync.Once
+	info *dataSchemaInfo
+}
+
+func loadDataSchemaInfo(v reflect.Type) *dataSchemaInfo {
+	entry, ok := dataSchemaCache.Load(v)
+	if !ok {
+		entry, _ = dataSchemaCache.LoadOrStore(v, &dataSchemaEntry{})
+	}
+	e := entry.(*dataSchemaEntry)
+	e.once.Do(func() {
+		info := &dataSchemaInfo{}
+		info.Fields = make([]*dataSchemaField, 0, v.NumField())
+		info.ByColumName = make(map[string]*dataSchemaField)
+		info.loadFields(v, nil)
+		e.info = info
+	})
+	return e.info
+}
+
+// Set the time field to t, a nil pointer field is set to a pointer to t
+func (field *dataSchemaField) setTime(elem reflect.Value, t time.Time) {
+	v := elem.FieldByIndex(field.FieldIndex)
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	v.Set(reflect.ValueOf(t))
+}
+
+// Set the time field to t, the time of a struct passed by value could not be set and is kept in stamps to be
+// passed as the argument of the column instead
+func (field *dataSchemaField) stampTime(elem reflect.Value, t time.Time, stamps map[*dataSchemaField]any) {
+	if elem.CanAddr() {
+		field.setTime(elem, t)
+		return
+	}
+	stamps[field] = t
+}
+
+// Get the value of the field to be stored, or its time kept in stamps
+func (field *dataSchemaField) stampedValue(elem reflect.Value, stamps map[*dataSchemaField]any) (interface{}, error) {
+	if t, ok := stamps[field]; ok {
+		return t, nil
+	}
+	return field.value(elem)
+}
+
+// Append the fields not in fields yet
+func appendMissingFields(fields []*dataSchemaField, more []*dataSchemaField) []*dataSchemaField {
+	for _, field := range more {
+		found := false
+		for _, f := range fields {
+			if f == field {
+				found = true
 			}
 		}
+		if !found {
+			fields = append(fields, field)
+		}
 	}
-	pInfo, _ := dataSchemaCache.LoadOrStore(v, &info)
-	return pInfo.(*dataSchemaInfo)
+	return fields
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
 
 func followPointer(v reflect.Value) reflect.Value {
@@ -302,15 +802,38 @@ func followPointer(v reflect.Value) reflect.Value {
 	return v
 }
 
+// The struct v points to, ErrNilPointer is returned for a nil pointer and ErrNotAStruct for other types
+func structElem(v any) (reflect.Value, error) {
+	elem := followPointer(reflect.ValueOf(v))
+	if elem.Kind() == reflect.Ptr {
+		return elem, errors.Wrapf(ErrNilPointer, "Invalid value of type %T", v)
+	}
+	if elem.Kind() != reflect.Struct {
+		return elem, errors.Wrapf(ErrNotAStruct, "Invalid value of type %T", v)
+	}
+	return elem, nil
+}
+
+// GetSchema returns the schema of the struct v, or nil if v is not a struct or a pointer to struct, or it could not be mapped.
+// Use ReadFromStruct to get the error instead.
 func GetSchema(v any) *Schema {
-	rv := reflect.ValueOf(v)
-	elem := followPointer(rv)
+	sc, _ := ReadFromStruct(v)
+	return sc
+}
 
-	if elem.Kind() != reflect.Struct /* || elem.IsNil() || !elem.IsValid()*/ {
-		return nil
+// ReadFromStruct returns the schema of the struct v, ErrNotAStruct is returned if v is not a struct or a pointer to struct,
+// ErrNilPointer if v is a nil pointer, and ErrUnexportedField if an unexported field has a db tag. The functions
+// reading or writing the rows of such a struct return ErrUnexportedField as well.
+func ReadFromStruct(v any) (*Schema, error) {
+	elem, e := structElem(v)
+	if e != nil {
+		return nil, e
 	}
 
 	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return nil, e
+	}
 
 	ret := &Schema{
 		Fields:  make([]Field, 0, len(schema.Fields)),
@@ -318,166 +841,831 @@ func GetSchema(v any) *Schema {
 	}
 	for i := 0; i < len(schema.Fields); i++ {
 		field := schema.Fields[i]
-		if field == nil {
-			continue
-		}
 		ret.Fields = append(ret.Fields, Field{
 			Name:          field.ColumnName,
 			Type:          field.DataStoreType,
 			Nullable:      field.IsNullable,
 			AutoIncrement: field.IsAutoincrement,
 			DefaultValue:  field.DefaultValue,
+			OnUpdate:      field.OnUpdate,
+			Charset:       field.Charset,
+			Collate:       field.Collate,
 			Comment:       field.Comment,
 		})
 
 		if field.IndexType != NONE {
+			column := field.ColumnName
+			if field.indexDesc {
+				column += " DESC"
+			}
 			for j := 0; j < len(ret.Indices); j++ {
 				index := &ret.Indices[j]
 				if index.Name == field.indexName {
-					index.Columns = append(index.Columns, field.ColumnName)
+					index.Columns = append(index.Columns, column)
+					if index.Type == "" {
+						index.Type = field.indexMethod
+					}
+					index.NullsNotDistinct = index.NullsNotDistinct || (field.IndexType == UNIQUE && field.indexNullsEqual)
+					if index.Comment == "" {
+						index.Comment = field.indexComment
+					}
 					goto indexDone
 				}
 			}
 			ret.Indices = append(ret.Indices, Index{
-				Name:    field.indexName,
-				Primary: field.IndexType == PRIMARY_KEY,
-				Unique:  field.IndexType == UNIQUE,
-				Columns: []string{field.ColumnName},
+				Name:             field.indexName,
+				Primary:          field.IndexType == PRIMARY_KEY,
+				Unique:           field.IndexType == UNIQUE,
+				Type:             field.indexMethod,
+				Columns:          []string{column},
+				NullsNotDistinct: field.IndexType == UNIQUE && field.indexNullsEqual,
+				Comment:          field.indexComment,
 			})
 		indexDone:
 		}
 	}
-	return ret
+
+	for _, field := range schema.Fields {
+		if field.ForeignKey != nil {
+			fk := *field.ForeignKey
+			fk.Columns = []string{field.ColumnName}
+			ret.ForeignKeys = append(ret.ForeignKeys, fk)
+		}
+		if field.check != "" {
+			ret.Checks = append(ret.Checks, Check{Column: field.ColumnName, Expression: field.check})
+		}
+	}
+
+	if primary := ret.Index("PRIMARY"); primary != nil {
+		primary.Columns = primary.Columns[:0]
+		for _, pk := range schema.primaryKeys() {
+			primary.Columns = append(primary.Columns, pk.ColumnName)
+		}
+	}
+	return ret, nil
+}
+
+// ColumnInfo describes how a struct field is mapped to a column
+type ColumnInfo struct {
+	Column          string // Name of the column in database
+	FieldName       string // Name of the field in struct
+	Type            string // Column type
+	SerializeMethod uint8  // NONE | ARRAY | JSON | YAML
+	Delimiter       string // Delimiter of the ARRAY serialize method
+	Nullable        bool
+	PrimaryKey      bool
+	AutoIncrement   bool
+	IndexType       uint8  // NONE | INDEX | UNIQUE | PRIMARY_KEY
+	IndexName       string // Name of the index the column belongs to, empty if IndexType is NONE
+	DefaultValue    string
+	Comment         string
+}
+
+// Describe returns the column mapping of the fields with a db tag in the struct v, in field order.
+// The errors are the ones of ReadFromStruct.
+func Describe(v any) ([]ColumnInfo, error) {
+	elem, e := structElem(v)
+	if e != nil {
+		return nil, e
+	}
+
+	schema := loadDataSchemaInfo(elem.Type())
+	if e := schema.err; e != nil {
+		return nil, e
+	}
+	ret := make([]ColumnInfo, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		ret = append(ret, ColumnInfo{
+			Column:          field.ColumnName,
+			FieldName:       field.Name,
+			Type:            field.DataStoreType,
+			SerializeMethod: field.SerializeMethod,
+			Delimiter:       field.SerializeDelimiter,
+			Nullable:        field.IsNullable,
+			PrimaryKey:      field.IsPrimaryKey,
+			AutoIncrement:   field.IsAutoincrement,
+			IndexType:       field.IndexType,
+			IndexName:       field.indexName,
+			DefaultValue:    field.DefaultValue,
+			Comment:         field.Comment,
+		})
+	}
+	return ret, nil
+}
+
+// Get the value of the field to be stored in the database, serialized if required
+func (field *dataSchemaField) value(elem reflect.Value) (interface{}, error) {
+	v := elem.FieldByIndex(field.FieldIndex)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch field.SerializeMethod {
+	case NONE:
+		if v.CanAddr() && !v.Type().Implements(valuerType) && v.Addr().Type().Implements(valuerType) {
+			// The Value method has a pointer receiver
+			return v.Addr().Interface(), nil
+		}
+		return v.Interface(), nil
+	case ARRAY:
+		data, e := encodeArray(v, field.SerializeDelimiter)
+		if e != nil {
+			return nil, errors.Wrapf(e, "Serialize column %s failed", field.ColumnName)
+		}
+		return data, nil
+	case JSON:
+		b, e := json.Marshal(v.Interface())
+		if e != nil {
+			return nil, errors.Wrapf(e, "Serialize column %s failed", field.ColumnName)
+		}
+		return string(b), nil
+	case YAML:
+		b, e := yaml.Marshal(v.Interface())
+		if e != nil {
+			return nil, errors.Wrapf(e, "Serialize column %s failed", field.ColumnName)
+		}
+		return string(b), nil
+	case TEXT:
+		if valuer, ok := v.Interface().(driver.Valuer); ok {
+			return valuer, nil
+		}
+		marshaler, ok := v.Interface().(encoding.TextMarshaler)
+		if !ok && v.CanAddr() {
+			marshaler, ok = v.Addr().Interface().(encoding.TextMarshaler)
+		}
+		if !ok {
+			return v.Interface(), nil
+		}
+		b, e := marshaler.MarshalText()
+		if e != nil {
+			return nil, errors.Wrapf(e, "Serialize column %s failed", field.ColumnName)
+		}
+		return string(b), nil
+	case BIT:
+		return v.Interface(), nil
+	case ENUM:
+		return field.encodeEnum(v)
+	}
+	return "", nil
+}
+
+// Set the field from the serialized data read from the database
+func (field *dataSchemaField) setValue(elem reflect.Value, data string) error {
+	v := elem.FieldByIndex(field.FieldIndex)
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	switch field.SerializeMethod {
+	case ARRAY:
+		arr, e := decodeArray(v.Type(), data, field.SerializeDelimiter)
+		if e != nil {
+			return errors.Wrapf(e, "Deserialize column %s failed", field.ColumnName)
+		}
+		v.Set(arr)
+	case JSON:
+		if e := json.Unmarshal([]byte(data), v.Addr().Interface()); e != nil {
+			return errors.Wrapf(e, "Deserialize column %s failed", field.ColumnName)
+		}
+	case YAML:
+		if e := yaml.Unmarshal([]byte(data), v.Addr().Interface()); e != nil {
+			return errors.Wrapf(e, "Deserialize column %s failed", field.ColumnName)
+		}
+	case TEXT:
+		if e := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(data)); e != nil {
+			return errors.Wrapf(e, "Deserialize column %s failed", field.ColumnName)
+		}
+	case BIT:
+		var n uint64
+		for i := 0; i < len(data); i++ {
+			n = n<<8 | uint64(data[i])
+		}
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(n != 0)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(n))
+		default:
+			v.SetUint(n)
+		}
+	case ENUM:
+		return field.decodeEnum(v, data)
+	}
+	return nil
 }
 
-func Insert(ctx context.Context, db *sql.DB, table string, v any) error {
-	rv := reflect.ValueOf(v)
-	elem := followPointer(rv)
+// How an INSERT statement handles the conflicting keys
+type insertMode int
+
+const (
+	insertDefault insertMode = iota // Fail with the duplicate key error
+	insertUpsert                    // Update the existing row
+	insertIgnore                    // Keep the existing row
+)
+
+func Insert(ctx context.Context, db Executor, table string, v any) error {
+	_, e := insert(ctx, db.ExecContext, queryRowOf(db), table, nil, v, insertDefault)
+	return e
+}
 
-	if elem.Kind() != reflect.Struct /* || elem.IsNil() || !elem.IsValid() */ {
-		return nil
+// InsertColumns inserts v with the given columns only, the other columns are filled by their defaults
+func InsertColumns(ctx context.Context, db Executor, table string, columns []string, v any) error {
+	_, e := insert(ctx, db.ExecContext, queryRowOf(db), table, columns, v, insertDefault)
+	return e
+}
+
+// Upsert inserts v, or updates the columns other than the primary key of the existing row if the
+// primary key conflicts. MySQL updates the row on the conflict of an unique index as well, while the
+// ON CONFLICT clause of PostgreSQL and SQLite targets the primary key, so the conflict of an unique index
+// fails there. The auto increment field is set if a new row is inserted, it's inserted as well if not
+// zero so that the row it identifies could be updated.
+func Upsert(ctx context.Context, db Executor, table string, v any) error {
+	_, e := insert(ctx, db.ExecContext, queryRowOf(db), table, nil, v, insertUpsert)
+	return e
+}
+
+// InsertIfNotExists inserts v unless the primary key or an unique index conflicts with an existing row,
+// which is kept as is. It reports whether the row is inserted, the check and the insert are done by a
+// single statement so there's no race between them.
+func InsertIfNotExists(ctx context.Context, db Executor, table string, v any) (bool, error) {
+	return insert(ctx, db.ExecContext, queryRowOf(db), table, nil, v, insertIgnore)
+}
+
+// Insert the columns of v, all columns except the auto increment one and the zero omitempty ones are inserted
+// if columns is empty.
+// The generated values are read back through queryRow if the dialect supports a RETURNING clause,
+// otherwise the auto increment field is set by the last insert id. It reports whether a row is inserted
+// or updated.
+func insert(ctx context.Context, exec execFunc, queryRow queryRowFunc, table string, columns []string, v any, mode insertMode) (bool, error) {
+	upsert := mode == insertUpsert
+	elem, e := structElem(v)
+	if e != nil {
+		return false, e
+	}
+	if e := beforeInsert(ctx, v, elem); e != nil {
+		return false, e
 	}
 
 	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return false, e
+	}
 
-	columns := make([]string, 0, len(schema.Fields))
-	values := make([]string, 0, len(schema.Fields))
-	args := make([]interface{}, 0, len(schema.Fields))
-	for i := 0; i < len(schema.Fields); i++ {
-		field := schema.Fields[i]
-		if field.IsAutoincrement {
-			continue
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return false, e
+	}
+
+	now := time.Now()
+	stamps := make(map[*dataSchemaField]any)
+	for _, field := range schema.AutoCreateFields {
+		if followPointer(elem.FieldByIndex(field.FieldIndex)).IsZero() {
+			field.stampTime(elem, now, stamps)
+		}
+	}
+	for _, field := range schema.AutoUpdateFields {
+		field.stampTime(elem, now, stamps)
+	}
+
+	fields := make([]*dataSchemaField, 0, len(schema.Fields))
+	if len(columns) == 0 {
+		for _, field := range schema.Fields {
+			if field.IsAutoincrement && !(upsert && !elem.FieldByIndex(field.FieldIndex).IsZero()) {
+				continue
+			}
+			if field.IsOmitEmpty && elem.FieldByIndex(field.FieldIndex).IsZero() {
+				continue
+			}
+			fields = append(fields, field)
+		}
+	} else {
+		for _, colName := range columns {
+			field := schema.ByColumName[colName]
+			if field == nil {
+				return false, errors.Wrapf(ErrUnknownColumn, "Unknown column %s", colName)
+			}
+			fields = append(fields, field)
+		}
+		fields = appendMissingFields(fields, schema.AutoCreateFields)
+		fields = appendMissingFields(fields, schema.AutoUpdateFields)
+	}
+
+	columns = make([]string, 0, len(fields))
+	values := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields))
+	updates := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !field.IsPrimaryKey && !field.IsAutoincrement && !field.IsAutoCreate {
+			updates = append(updates, field.ColumnName)
 		}
 		columns = append(columns, field.ColumnName)
-		values = append(values, "?")
-		switch field.SerializeMethod {
-		case NONE:
-			args = append(args, elem.Field(field.FieldIndex).Interface())
-		case ARRAY:
-			args = append(args, strings.Join(elem.Field(field.FieldIndex).Interface().([]string), field.SerializeDelimiter))
-		case JSON:
-			b, _ := json.Marshal(elem.Field(field.FieldIndex).Interface())
-			args = append(args, string(b))
-		case YAML:
-			b, _ := yaml.Marshal(elem.Field(field.FieldIndex).Interface())
-			args = append(args, string(b))
-		default:
-			args = append(args, "")
+		values = append(values, DefaultDialect.Placeholder(len(values)+1))
+		arg, e := field.stampedValue(elem, stamps)
+		if e != nil {
+			return false, e
+		}
+		args = append(args, arg)
+	}
+
+	query := "INSERT INTO " + tableName + " (" + quoteColumns(DefaultDialect, columns) + ") VALUES (" + strings.Join(values, ",") + ")"
+	if upsert {
+		keys := make([]string, 0, 1)
+		for _, pk := range schema.primaryKeys() {
+			keys = append(keys, pk.ColumnName)
 		}
+		if len(keys) == 0 {
+			return false, ErrNoPrimaryKey
+		}
+		query += DefaultDialect.UpsertClause(keys, updates)
+	} else if mode == insertIgnore {
+		query = DefaultDialect.InsertIgnore(query)
 	}
 
-	r, e := db.ExecContext(ctx, "INSERT INTO `"+table+"` (`"+strings.Join(columns, "`,`")+"`) VALUES ("+strings.Join(values, ",")+")", args...)
+	// The auto increment column and the omitted columns with a default value are generated by the server. The auto
+	// increment column is returned even if it's given, the dialects with RETURNING may not support LastInsertId.
+	returning := make([]*dataSchemaField, 0, 1)
+	for _, field := range schema.Fields {
+		if field.SerializeMethod != NONE || (containsString(columns, field.ColumnName) && !field.IsAutoincrement) {
+			continue
+		}
+		// DEFAULT NULL is not read back, the field may not hold a NULL
+		if field.IsAutoincrement || normalizeDefault(field.DefaultValue) != "" {
+			returning = append(returning, field)
+		}
+	}
+	if len(returning) > 0 {
+		returningColumns := make([]string, 0, len(returning))
+		for _, field := range returning {
+			returningColumns = append(returningColumns, field.ColumnName)
+		}
+		if clause := DefaultDialect.ReturningClause(returningColumns); clause != "" {
+			// The returned columns could only be read into a struct passed by pointer
+			if !elem.CanAddr() {
+				return false, errors.Wrapf(ErrNotAddressable, "Invalid value of type %T", v)
+			}
+			dest := make([]any, 0, len(returning))
+			for _, field := range returning {
+				dest = append(dest, elem.FieldByIndex(field.FieldIndex).Addr().Interface())
+			}
+			query += clause
+			e := queryRow(ctx, query, args...).Scan(dest...)
+			logQuery(ctx, query, args, e)
+			// No row is returned if the conflicting row is kept
+			if e == sql.ErrNoRows {
+				return false, nil
+			}
+			if e != nil {
+				return false, errors.Wrap(e, "Insert failed")
+			}
+			return true, nil
+		}
+	}
+
+	r, e := execLogged(ctx, exec, query, args...)
 	if e != nil {
-		return errors.Wrap(e, "Insert failed")
+		return false, errors.Wrap(e, "Insert failed")
+	}
+	if mode == insertIgnore {
+		n, e := r.RowsAffected()
+		if e != nil {
+			return false, errors.Wrap(e, "Get affected rows failed")
+		}
+		if n == 0 {
+			return false, nil
+		}
 	}
 
 	if schema.AIField != nil {
 		idx, e := r.LastInsertId()
 		if e != nil {
-			return errors.Wrap(e, "Get last insert id failed")
+			return false, errors.Wrap(e, "Get last insert id failed")
+		}
+		// The last insert id is 0 if an existing row was updated
+		if idx != 0 {
+			elem.FieldByIndex(schema.AIField.FieldIndex).SetInt(idx)
 		}
-		elem.Field(schema.AIField.FieldIndex).SetInt(idx)
 	}
 
-	return nil
+	return true, nil
+}
+
+func Update(ctx context.Context, db Executor, table string, columns []string, v any) error {
+	_, e := update(ctx, db.ExecContext, table, columns, "", nil, v)
+	return e
 }
 
-func Update(ctx context.Context, db *sql.DB, table string, columns []string, v any) error {
-	rv := reflect.ValueOf(v)
-	elem := followPointer(rv)
+// UpdateResult is Update returning the number of affected rows, 0 if no row matches the primary key.
+// MySQL only counts the rows actually changed unless the clientFoundRows parameter is set in the DSN.
+func UpdateResult(ctx context.Context, db Executor, table string, columns []string, v any) (int64, error) {
+	return update(ctx, db.ExecContext, table, columns, "", nil, v)
+}
+
+// UpdateWhere updates the columns of the rows matching the where condition with the values of v instead of
+// the row identified by the primary key, all columns except the primary keys, the auto increment one and the autocreate ones are
+// updated if columns is empty. The version column is increased but not checked, ErrEmptyCondition is
+// returned if where is empty. With numbered placeholders like the ones of PostgreSQL, the placeholders in
+// where are numbered after the ones of the columns.
+func UpdateWhere(ctx context.Context, db Executor, table string, columns []string, where string, whereArgs []any, v any) error {
+	if where == "" {
+		return ErrEmptyCondition
+	}
+	_, e := update(ctx, db.ExecContext, table, columns, where, whereArgs, v)
+	return e
+}
 
-	if elem.Kind() != reflect.Struct /* || elem.IsNil() || !elem.IsValid() */ {
-		return nil
+// Update the columns of v, the row is identified by the primary key if where is empty
+func update(ctx context.Context, exec execFunc, table string, columns []string, where string, whereArgs []any, v any) (int64, error) {
+	elem, e := structElem(v)
+	if e != nil {
+		return 0, e
 	}
 
 	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return 0, e
+	}
 
 	if len(columns) == 0 {
 		columns = make([]string, 0, len(schema.Fields))
 		for _, field := range schema.Fields {
-			if field.IsPrimaryKey || field.IsAutoincrement {
+			if field.IsPrimaryKey || field.IsAutoincrement || field.IsAutoCreate {
 				continue
 			}
 			columns = append(columns, field.ColumnName)
 		}
+	} else if len(schema.AutoUpdateFields) > 0 {
+		columns = append([]string{}, columns...)
+		for _, field := range schema.AutoUpdateFields {
+			if !containsString(columns, field.ColumnName) {
+				columns = append(columns, field.ColumnName)
+			}
+		}
 	}
 
-	pks := make([]*dataSchemaField, 0, 4)
-	for _, field := range schema.Fields {
-		if field.IsPrimaryKey {
-			pks = append(pks, field)
-		}
+	now := time.Now()
+	stamps := make(map[*dataSchemaField]any)
+	for _, field := range schema.AutoUpdateFields {
+		field.stampTime(elem, now, stamps)
+	}
+
+	pks := schema.primaryKeys()
+	if len(pks) == 0 && where == "" {
+		return 0, ErrNoPrimaryKey
+	}
+
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return 0, e
 	}
 
-	sql := "update `" + table + "` set "
+	sql := "update " + tableName + " set "
 	args := make([]interface{}, 0, len(schema.Fields))
 	for _, colName := range columns {
-		sql += "`" + colName + "`=?,"
 		field := schema.ByColumName[colName]
 		if field == nil {
-			return errors.Wrapf(ErrUnknownColumn, "Unknown column %s", colName)
+			return 0, errors.Wrapf(ErrUnknownColumn, "Unknown column %s", colName)
+		}
+		if field.IsVersion {
+			// The version is always increased below
+			continue
 		}
+		sql += DefaultDialect.QuoteIdent(colName) + "=" + DefaultDialect.Placeholder(len(args)+1) + ","
 
-		switch field.SerializeMethod {
-		case NONE:
-			args = append(args, elem.Field(field.FieldIndex).Interface())
-		case ARRAY:
-			args = append(args, strings.Join(elem.Field(field.FieldIndex).Interface().([]string), field.SerializeDelimiter))
-		case JSON:
-			b, _ := json.Marshal(elem.Field(field.FieldIndex).Interface())
-			args = append(args, string(b))
-		case YAML:
-			b, _ := yaml.Marshal(elem.Field(field.FieldIndex).Interface())
-			args = append(args, string(b))
-		default:
-			args = append(args, "")
+		arg, e := field.stampedValue(elem, stamps)
+		if e != nil {
+			return 0, e
+		}
+		args = append(args, arg)
+	}
+
+	version := schema.VersionField
+	if version != nil {
+		column := DefaultDialect.QuoteIdent(version.ColumnName)
+		sql += column + "=" + column + "+1,"
+	}
+
+	if where != "" {
+		sql = sql[:len(sql)-1] + " where " + where
+		args = append(args, whereArgs...)
+		r, e := execLogged(ctx, exec, sql, args...)
+		if e != nil {
+			return 0, errors.Wrap(e, "Update failed")
 		}
+		n, e := r.RowsAffected()
+		if e != nil {
+			return 0, errors.Wrap(e, "Get affected rows failed")
+		}
+		return n, nil
 	}
 
 	sql = sql[:len(sql)-1] + " where "
 	for _, pk := range pks {
-		sql += "`" + pk.ColumnName + "`=? and "
-		args = append(args, elem.Field(pk.FieldIndex).Interface())
+		sql += DefaultDialect.QuoteIdent(pk.ColumnName) + "=" + DefaultDialect.Placeholder(len(args)+1) + " and "
+		args = append(args, elem.FieldByIndex(pk.FieldIndex).Interface())
+	}
+	if version != nil {
+		sql += DefaultDialect.QuoteIdent(version.ColumnName) + "=" + DefaultDialect.Placeholder(len(args)+1) + " and "
+		args = append(args, elem.FieldByIndex(version.FieldIndex).Interface())
 	}
 	sql = sql[:len(sql)-5]
 
-	_, e := db.ExecContext(ctx, sql, args...)
+	r, e := execLogged(ctx, exec, sql, args...)
 	if e != nil {
-		return errors.Wrap(e, "Update failed")
+		return 0, errors.Wrap(e, "Update failed")
 	}
 
+	n, e := r.RowsAffected()
+	if e != nil {
+		return 0, errors.Wrap(e, "Get affected rows failed")
+	}
+
+	if version != nil {
+		if n == 0 {
+			return 0, ErrVersionConflict
+		}
+		// The version of a struct passed by value could not be increased
+		if !elem.CanAddr() {
+			return n, nil
+		}
+		v := elem.FieldByIndex(version.FieldIndex)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(v.Int() + 1)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.SetUint(v.Uint() + 1)
+		}
+	}
+	return n, nil
+}
+
+// Get loads the row identified by the primary key of v into v, returns sql.ErrNoRows if no row matches
+func Get(ctx context.Context, db Executor, table string, v any) error {
+	elem, e := structElem(v)
+	if e != nil {
+		return e
+	}
+
+	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return e
+	}
+
+	pks := schema.primaryKeys()
+	if len(pks) == 0 {
+		return ErrNoPrimaryKey
+	}
+
+	columns := make([]string, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		columns = append(columns, field.ColumnName)
+	}
+
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return e
+	}
+
+	query := "select " + quoteColumns(DefaultDialect, columns) + " from " + tableName + " where "
+	args := make([]interface{}, 0, len(pks))
+	for _, pk := range pks {
+		query += DefaultDialect.QuoteIdent(pk.ColumnName) + "=" + DefaultDialect.Placeholder(len(args)+1) + " and "
+		args = append(args, elem.FieldByIndex(pk.FieldIndex).Interface())
+	}
+	query = query[:len(query)-5]
+
+	rows, e := db.QueryContext(ctx, query, args...)
+	logQuery(ctx, query, args, e)
+	if e != nil {
+		return errors.Wrap(e, "Get failed")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if e := rows.Err(); e != nil {
+			return errors.Wrap(e, "Get failed")
+		}
+		return sql.ErrNoRows
+	}
+	return scanRow(ctx, rows, v, false)
+}
+
+// DeleteOption changes the behavior of Delete
+type DeleteOption uint8
+
+const (
+	// DeleteMustExist makes Delete fail with ErrNoRowsAffected if no row was deleted
+	DeleteMustExist DeleteOption = iota + 1
+)
+
+// Delete deletes the row identified by the primary key of v
+func Delete(ctx context.Context, db Executor, table string, v any, opts ...DeleteOption) error {
+	elem, e := structElem(v)
+	if e != nil {
+		return e
+	}
+
+	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return e
+	}
+
+	pks := schema.primaryKeys()
+	if len(pks) == 0 {
+		return ErrNoPrimaryKey
+	}
+
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return e
+	}
+
+	sql := "delete from " + tableName + " where "
+	args := make([]interface{}, 0, len(pks))
+	for _, pk := range pks {
+		sql += DefaultDialect.QuoteIdent(pk.ColumnName) + "=" + DefaultDialect.Placeholder(len(args)+1) + " and "
+		args = append(args, elem.FieldByIndex(pk.FieldIndex).Interface())
+	}
+	sql = sql[:len(sql)-5]
+
+	r, e := execLogged(ctx, db.ExecContext, sql, args...)
+	if e != nil {
+		return errors.Wrap(e, "Delete failed")
+	}
+
+	for _, opt := range opts {
+		if opt == DeleteMustExist {
+			n, e := r.RowsAffected()
+			if e != nil {
+				return errors.Wrap(e, "Get affected rows failed")
+			}
+			if n == 0 {
+				return ErrNoRowsAffected
+			}
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of rows in the table matching the where clause, all rows are counted if where is empty.
+// The where clause is appended as is, the values should be passed as args through placeholders.
+func Count(ctx context.Context, db Executor, table string, where string, args ...any) (int64, error) {
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return 0, e
+	}
+
+	query := "select count(*) from " + tableName
+	if where != "" {
+		query += " where " + where
+	}
+
+	var n int64
+	e = db.QueryRowContext(ctx, query, args...).Scan(&n)
+	logQuery(ctx, query, args, e)
+	if e != nil {
+		return 0, errors.Wrap(e, "Count failed")
+	}
+	return n, nil
+}
+
+// ListOptions filters, sorts and pages the rows read by List
+type ListOptions struct {
+	Where   string // The where clause appended as is, the values should be passed as Args through placeholders
+	Args    []any
+	OrderBy string // The order by clause appended as is, e.g. "id DESC"
+	Limit   int    // The maximum number of rows, all rows are read if 0
+	Offset  int    // The number of rows skipped, only used with Limit
+}
+
+// List reads the rows of the table into dest, which is a pointer to a slice of structs or pointers to structs.
+// The columns are the ones of the struct, the slice is reset before the rows are appended.
+func List(ctx context.Context, db Executor, table string, dest any, opts ListOptions) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return errors.Wrapf(ErrNotAStruct, "Invalid destination of type %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.Wrapf(ErrNotAStruct, "Invalid destination of type %T", dest)
+	}
+
+	schema := loadDataSchemaInfo(structType)
+	if e := schema.err; e != nil {
+		return e
+	}
+	columns := make([]string, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		columns = append(columns, field.ColumnName)
+	}
+
+	tableName, e := quoteTableName(DefaultDialect, table)
+	if e != nil {
+		return e
+	}
+
+	query := "select " + quoteColumns(DefaultDialect, columns) + " from " + tableName
+	args := append(make([]any, 0, len(opts.Args)+2), opts.Args...)
+	if opts.Where != "" {
+		query += " where " + opts.Where
+	}
+	if opts.OrderBy != "" {
+		query += " order by " + opts.OrderBy
+	}
+	if opts.Limit > 0 {
+		query += " limit " + DefaultDialect.Placeholder(len(args)+1)
+		args = append(args, opts.Limit)
+		if opts.Offset > 0 {
+			query += " offset " + DefaultDialect.Placeholder(len(args)+1)
+			args = append(args, opts.Offset)
+		}
+	}
+
+	rows, e := db.QueryContext(ctx, query, args...)
+	logQuery(ctx, query, args, e)
+	if e != nil {
+		return errors.Wrap(e, "List failed")
+	}
+	defer rows.Close()
+
+	slice.Set(slice.Slice(0, 0))
+	for rows.Next() {
+		item := reflect.New(structType)
+		if e := scanRow(ctx, rows, item.Interface(), false); e != nil {
+			return e
+		}
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return errors.Wrap(e, "List failed")
+	}
 	return nil
 }
 
+// ScanRrow is the misspelled name of ScanRow, kept for compatibility.
+//
+// Deprecated: Use ScanRow instead.
 func ScanRrow(row *sql.Rows, v any) error {
-	rv := reflect.ValueOf(v)
-	elem := followPointer(rv)
+	return ScanRow(row, v)
+}
+
+// ScanRow scans the current row into the struct v, the columns are matched by the column names,
+// ErrUnknownColumn is returned if a column has no field in v. The fields without a column in the row,
+// including the serialized ones, are left untouched, so a struct could be scanned from a partial select.
+func ScanRow(row *sql.Rows, v any) error {
+	return scanRow(context.Background(), row, v, false)
+}
+
+// ScanRowLenient is ScanRow ignoring the columns without a field in v, so a struct with some of the
+// columns could be scanned from SELECT *
+func ScanRowLenient(row *sql.Rows, v any) error {
+	return scanRow(context.Background(), row, v, true)
+}
+
+// ScanRowMap scans the current row into a map from the column names to the values, no struct or schema is
+// needed. The values are the ones returned by the driver, e.g. MySQL returns []byte for the text columns
+// unless the column types are known to the driver.
+func ScanRowMap(row *sql.Rows) (map[string]any, error) {
+	columns, e := row.Columns()
+	if e != nil {
+		return nil, errors.Wrap(e, "Get table columns failed")
+	}
+	values := make([]any, len(columns))
+	scanArgs := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	if e := row.Scan(scanArgs...); e != nil {
+		return nil, errors.Wrap(e, "Scan table columns failed")
+	}
+	ret := make(map[string]any, len(columns))
+	for i, column := range columns {
+		ret[column] = values[i]
+	}
+	return ret, nil
+}
 
-	if elem.Kind() != reflect.Struct /* || elem.IsNil() || !elem.IsValid() */ {
-		return nil
+func scanRow(ctx context.Context, row *sql.Rows, v any, lenient bool) error {
+	elem, e := structElem(v)
+	if e != nil {
+		return e
 	}
 
 	schema := loadDataSchemaInfo(reflect.TypeOf(elem.Interface()))
+	if e := schema.err; e != nil {
+		return e
+	}
 
 	columns, error := row.Columns()
 	if error != nil {
@@ -486,7 +1674,7 @@ func ScanRrow(row *sql.Rows, v any) error {
 
 	type serializeFieldInfo struct {
 		field *dataSchemaField
-		data  string
+		data  sql.NullString
 	}
 
 	serializedFields := make([]*serializeFieldInfo, 0)
@@ -494,14 +1682,29 @@ func ScanRrow(row *sql.Rows, v any) error {
 	for _, colName := range columns {
 		col := schema.ByColumName[colName]
 		if col == nil {
+			if lenient {
+				scanArgs = append(scanArgs, new(sql.RawBytes))
+				continue
+			}
 			return errors.Wrapf(ErrUnknownColumn, "Unknown column %s", colName)
 		}
 		if col.SerializeMethod == NONE {
-			scanArgs = append(scanArgs, elem.Field(col.FieldIndex).Addr().Interface())
+			// A pointer field is scanned as **T, it's set to nil for NULL and allocated otherwise
+			f := elem.FieldByIndex(col.FieldIndex)
+			if isBytesType(f.Type()) && f.Type() != bytesType && !f.Addr().Type().Implements(scannerType) {
+				// A named byte slice is scanned as *[]byte, so the bytes are copied instead of referring to the buffer of the driver
+				scanArgs = append(scanArgs, f.Addr().Convert(reflect.PtrTo(bytesType)).Interface())
+				continue
+			}
+			if f.Kind() == reflect.Bool && f.Type() != boolType && !f.Addr().Type().Implements(scannerType) {
+				// The driver converts the integers to bool for *bool only, a named bool is scanned as *bool
+				scanArgs = append(scanArgs, f.Addr().Convert(reflect.PtrTo(boolType)).Interface())
+				continue
+			}
+			scanArgs = append(scanArgs, f.Addr().Interface())
 		} else {
 			sfi := &serializeFieldInfo{
 				field: col,
-				data:  "",
 			}
 			serializedFields = append(serializedFields, sfi)
 			scanArgs = append(scanArgs, &sfi.data)
@@ -513,16 +1716,15 @@ func ScanRrow(row *sql.Rows, v any) error {
 	}
 
 	for _, sfi := range serializedFields {
-		switch sfi.field.SerializeMethod {
-		case ARRAY:
-			a := strings.Split(sfi.data, sfi.field.SerializeDelimiter)
-			elem.Field(sfi.field.FieldIndex).Set(reflect.ValueOf(a))
-		case JSON:
-			json.Unmarshal([]byte(sfi.data), elem.Field(sfi.field.FieldIndex).Addr().Interface())
-		case YAML:
-			yaml.Unmarshal([]byte(sfi.data), elem.Field(sfi.field.FieldIndex).Addr().Interface())
+		if !sfi.data.Valid {
+			f := elem.FieldByIndex(sfi.field.FieldIndex)
+			f.Set(reflect.Zero(f.Type()))
+			continue
+		}
+		if e := sfi.field.setValue(elem, sfi.data.String); e != nil {
+			return e
 		}
 	}
 
-	return nil
+	return afterScan(ctx, v, elem)
 }
@@ -0,0 +1,495 @@
+package sqlschema
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadFromDBCreate reads the columns, indices and constraints of the table by parsing the output of SHOW CREATE TABLE
+// instead of querying information_schema, which is slow on the servers with thousands of tables. Only those queries
+// are saved, the table options are still read by ReadTable from the rows of the table in information_schema TABLES
+// and PARTITIONS, which also tell whether the table exists, as SHOW CREATE TABLE omits the default collation on
+// MySQL 5.7. It's supported by MySQL only and returns nil if the table does not exist. The charset and collation of
+// a column are empty if they're the defaults of the table. MySQL before 8.0.16 drops the checks silently, they are
+// left alone by Update then.
+func ReadFromDBCreate(db Executor, ctx context.Context, name string) (*Schema, error) {
+	return readFromDBCreate(db, ctx, DefaultDialect, name)
+}
+
+func readFromDBCreate(db Executor, ctx context.Context, d Dialect, name string) (*Schema, error) {
+	md, ok := d.(mysqlDialect)
+	if !ok {
+		return nil, errors.Wrap(ErrNotSupported, "SHOW CREATE TABLE")
+	}
+	sc := &Schema{Name: name, Dialect: d}
+	if ok, e := d.ReadTable(db, ctx, sc); e != nil || !ok {
+		return nil, e
+	}
+	// The checks are left alone by Update if the server does not report them
+	if ok, e := md.checksSupported(db, ctx); e != nil {
+		return nil, e
+	} else if !ok {
+		sc.checksUnknown = true
+	}
+
+	var table, stmt string
+	if e := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteSchemaTable(d, name)).Scan(&table, &stmt); e != nil {
+		return nil, errors.Wrap(e, "Show create table failed")
+	}
+	created, e := parseCreateTable(stmt)
+	if e != nil {
+		return nil, e
+	}
+	sc.Fields, sc.Indices, sc.ForeignKeys, sc.Checks = created.Fields, created.Indices, created.ForeignKeys, created.Checks
+	return sc, nil
+}
+
+// Parse the CREATE TABLE statement shown by MySQL, the identifiers could be quoted by back quotes or double quotes
+// in the ANSI_QUOTES mode. The definitions not described by the schema, like generated columns, are ignored.
+func parseCreateTable(stmt string) (*Schema, error) {
+	tokens := createTableTokens(stmt)
+	if len(tokens) < 4 || !strings.EqualFold(tokens[0], "CREATE") || !strings.EqualFold(tokens[1], "TABLE") || !strings.HasPrefix(tokens[3], "(") {
+		return nil, errors.Errorf("Invalid create table statement %s", stmt)
+	}
+	sc := &Schema{Name: unquoteIdent(tokens[2]), Fields: make([]Field, 0), Indices: make([]Index, 0), ForeignKeys: make([]ForeignKey, 0), Checks: make([]Check, 0)}
+	for _, def := range splitTokens(createTableTokens(unwrapGroup(tokens[3])), ",") {
+		if len(def) == 0 {
+			continue
+		}
+		if e := parseCreateDefinition(sc, def); e != nil {
+			return nil, errors.Wrapf(e, "Parse definition %s failed", strings.Join(def, " "))
+		}
+	}
+	if e := parseTableOptions(sc, tokens[4:]); e != nil {
+		return nil, e
+	}
+	return sc, nil
+}
+
+// Parse a column, an index or a constraint of the table
+func parseCreateDefinition(sc *Schema, def []string) error {
+	switch strings.ToUpper(def[0]) {
+	case "PRIMARY", "UNIQUE", "KEY", "INDEX", "FULLTEXT", "SPATIAL":
+		return parseIndexDefinition(sc, def)
+	case "CONSTRAINT":
+		return parseConstraintDefinition(sc, def)
+	case "CHECK", "FOREIGN":
+		// Constraints without a name are named by the server, it never shows them this way
+		return errors.Errorf("Unnamed constraint")
+	}
+
+	field := Field{Name: unquoteIdent(def[0]), Nullable: true}
+	if len(def) < 2 {
+		return errors.Errorf("Missing column type")
+	}
+	field.Type = def[1]
+	i := 2
+	for ; i < len(def) && (strings.EqualFold(def[i], "unsigned") || strings.EqualFold(def[i], "zerofill")); i++ {
+		field.Type += " " + def[i]
+	}
+	for ; i < len(def); i++ {
+		next := ""
+		if i+1 < len(def) {
+			next = def[i+1]
+		}
+		switch word := strings.ToUpper(def[i]); {
+		case word == "CHARACTER" && strings.EqualFold(next, "SET") && i+2 < len(def):
+			field.Charset = def[i+2]
+			i += 2
+		case word == "COLLATE" && next != "":
+			field.Collate = next
+			i++
+		case word == "NOT" && strings.EqualFold(next, "NULL"):
+			field.Nullable = false
+			i++
+		case word == "NULL":
+			field.Nullable = true
+		case word == "AUTO_INCREMENT":
+			field.AutoIncrement = true
+		case word == "DEFAULT" && next != "":
+			// Literals are unquoted as information_schema reports them
+			if strings.HasPrefix(next, "'") {
+				field.DefaultValue = unquoteString(next)
+			} else if !strings.EqualFold(next, "NULL") {
+				field.DefaultValue = next
+			}
+			i++
+		case word == "ON" && strings.EqualFold(next, "UPDATE") && i+2 < len(def):
+			field.OnUpdate = strings.ToUpper(def[i+2])
+			i += 2
+		case word == "COMMENT" && next != "":
+			field.Comment = unquoteString(next)
+			i++
+		}
+	}
+	sc.Fields = append(sc.Fields, field)
+	return nil
+}
+
+func parseIndexDefinition(sc *Schema, def []string) error {
+	index := Index{Type: "BTREE"}
+	i := 0
+	switch strings.ToUpper(def[0]) {
+	case "PRIMARY":
+		index.Primary = true
+		index.Name = "PRIMARY"
+	case "UNIQUE":
+		index.Unique = true
+	case "FULLTEXT", "SPATIAL":
+		index.Type = strings.ToUpper(def[0])
+	}
+	// Skip the keywords before the name, e.g. UNIQUE KEY
+	for i < len(def) && !strings.HasPrefix(def[i], "(") && !isQuotedIdent(def[i]) {
+		i++
+	}
+	if i < len(def) && isQuotedIdent(def[i]) {
+		index.Name = unquoteIdent(def[i])
+		i++
+	}
+	if i >= len(def) || !strings.HasPrefix(def[i], "(") {
+		return errors.Errorf("Missing index columns")
+	}
+	for _, part := range splitTokens(createTableTokens(unwrapGroup(def[i])), ",") {
+		column := ""
+		for _, token := range part {
+			switch {
+			case isQuotedIdent(token):
+				column += unquoteIdent(token)
+			case strings.EqualFold(token, "DESC"):
+				column += " DESC"
+			case strings.HasPrefix(token, "("):
+				column += token
+			}
+		}
+		index.Columns = append(index.Columns, column)
+	}
+	for i++; i < len(def); i++ {
+		switch strings.ToUpper(def[i]) {
+		case "USING":
+			if i+1 < len(def) {
+				index.Type = strings.ToUpper(def[i+1])
+				i++
+			}
+		case "COMMENT":
+			if i+1 < len(def) {
+				index.Comment = unquoteString(def[i+1])
+				i++
+			}
+		}
+	}
+	sc.Indices = append(sc.Indices, index)
+	return nil
+}
+
+func parseConstraintDefinition(sc *Schema, def []string) error {
+	if len(def) < 4 {
+		return errors.Errorf("Invalid constraint")
+	}
+	name := unquoteIdent(def[1])
+	switch strings.ToUpper(def[2]) {
+	case "CHECK":
+		sc.Checks = append(sc.Checks, Check{Name: name, Expression: unwrapGroup(def[3])})
+		return nil
+	case "FOREIGN":
+		fk := ForeignKey{Name: name}
+		i := 3
+		if strings.EqualFold(def[i], "KEY") {
+			i++
+		}
+		if i+2 >= len(def) || !strings.EqualFold(def[i+1], "REFERENCES") {
+			return errors.Errorf("Invalid foreign key")
+		}
+		for _, column := range splitTokens(createTableTokens(unwrapGroup(def[i])), ",") {
+			fk.Columns = append(fk.Columns, unquoteIdent(column[0]))
+		}
+		i += 2
+		fk.RefTable = unquoteIdent(def[i])
+		// The referenced table is qualified by the database if it's in another one
+		if i+2 < len(def) && def[i+1] == "." {
+			fk.RefTable += "." + unquoteIdent(def[i+2])
+			i += 2
+		}
+		if i+1 >= len(def) || !strings.HasPrefix(def[i+1], "(") {
+			return errors.Errorf("Missing referenced columns")
+		}
+		for _, column := range splitTokens(createTableTokens(unwrapGroup(def[i+1])), ",") {
+			fk.RefColumns = append(fk.RefColumns, unquoteIdent(column[0]))
+		}
+		for i += 2; i+2 < len(def); i++ {
+			if !strings.EqualFold(def[i], "ON") {
+				continue
+			}
+			action := strings.ToUpper(def[i+2])
+			// SET NULL, SET DEFAULT and NO ACTION are two words
+			if (action == "SET" || action == "NO") && i+3 < len(def) {
+				action += " " + strings.ToUpper(def[i+3])
+			}
+			if strings.EqualFold(def[i+1], "DELETE") {
+				fk.OnDelete = action
+			} else if strings.EqualFold(def[i+1], "UPDATE") {
+				fk.OnUpdate = action
+			}
+		}
+		// The server reports the default action as RESTRICT
+		if fk.OnDelete == "" {
+			fk.OnDelete = "RESTRICT"
+		}
+		if fk.OnUpdate == "" {
+			fk.OnUpdate = "RESTRICT"
+		}
+		sc.ForeignKeys = append(sc.ForeignKeys, fk)
+		return nil
+	}
+	return errors.Errorf("Unknown constraint %s", def[2])
+}
+
+// Parse the table options after the definitions, e.g. ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='...'
+func parseTableOptions(sc *Schema, tokens []string) error {
+	for i := 0; i < len(tokens); i++ {
+		key := strings.ToUpper(tokens[i])
+		if key == "PARTITION" {
+			return parsePartitioning(sc, tokens[i+1:])
+		}
+		if i+2 >= len(tokens) || tokens[i+1] != "=" {
+			continue
+		}
+		value := tokens[i+2]
+		switch key {
+		case "ENGINE":
+			sc.Engine = value
+		case "COLLATE":
+			sc.Collate = value
+		case "ROW_FORMAT":
+			sc.RowFormat = value
+		case "COMMENT":
+			sc.Comment = unquoteString(value)
+		case "AUTO_INCREMENT":
+			n, e := strconv.ParseInt(value, 10, 64)
+			if e != nil {
+				return errors.Wrap(e, "Parse auto increment failed")
+			}
+			sc.AutoIncrement = n
+		}
+		i += 2
+	}
+	return nil
+}
+
+// Parse the partitioning after PARTITION, e.g. BY RANGE (year(`created`)) (PARTITION p0 VALUES LESS THAN (2024),...)
+func parsePartitioning(sc *Schema, tokens []string) error {
+	if len(tokens) == 0 || !strings.EqualFold(tokens[0], "BY") {
+		return errors.Errorf("Invalid partitioning %s", strings.Join(tokens, " "))
+	}
+	p := &Partitioning{}
+	i := 1
+	// The method is followed by the expression, which is kept in the word of RANGE COLUMNS(`a`)
+	for ; i < len(tokens); i++ {
+		word := tokens[i]
+		j := strings.Index(word, "(")
+		if j >= 0 {
+			p.Expression = unwrapGroup(word[j:])
+			word = word[:j]
+		}
+		if word != "" {
+			p.Method = strings.TrimSpace(p.Method + " " + strings.ToUpper(word))
+		}
+		if j >= 0 {
+			break
+		}
+	}
+	for i++; i < len(tokens); i++ {
+		switch {
+		case strings.EqualFold(tokens[i], "PARTITIONS") && i+1 < len(tokens):
+			n, e := strconv.Atoi(tokens[i+1])
+			if e != nil {
+				return errors.Wrap(e, "Parse partitions failed")
+			}
+			p.Count = n
+			i++
+		case strings.HasPrefix(tokens[i], "("):
+			for _, def := range splitTokens(createTableTokens(unwrapGroup(tokens[i])), ",") {
+				if len(def) < 2 || !strings.EqualFold(def[0], "PARTITION") {
+					continue
+				}
+				part := Partition{Name: unquoteIdent(def[1])}
+				// VALUES LESS THAN (2024), VALUES LESS THAN MAXVALUE or VALUES IN (1,2)
+				for j := 2; j < len(def); j++ {
+					if strings.EqualFold(def[j], "THAN") || strings.EqualFold(def[j], "IN") {
+						if j+1 < len(def) {
+							part.Values = unwrapGroup(def[j+1])
+						}
+						break
+					}
+				}
+				if isHashPartitioning(p.Method) {
+					p.Count++
+				} else {
+					p.Partitions = append(p.Partitions, part)
+				}
+			}
+		}
+	}
+	if p.Method == "" {
+		return errors.Errorf("Invalid partitioning %s", strings.Join(tokens, " "))
+	}
+	sc.Partitioning = p
+	return nil
+}
+
+// Split a statement into tokens: quoted identifiers, string literals, parenthesized groups, words and the
+// punctuations , = and . A group directly following a word is kept in the word, e.g. varchar(255), and so is a
+// string literal, e.g. b'1'. The versioned comments like /*!80016 NOT ENFORCED */ are read as normal text.
+func createTableTokens(s string) []string {
+	tokens := make([]string, 0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "/*!"):
+			i += 3
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "*/"):
+			i += 2
+		case c == ',' || c == '=' || c == '.':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case c == '`' || c == '"' || c == '\'':
+			end := skipQuoted(s, i)
+			tokens = append(tokens, s[i:end])
+			i = end
+		case c == '(':
+			end := skipGroup(s, i)
+			tokens = append(tokens, s[i:end])
+			i = end
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r,=`\")", rune(s[i])) {
+				if s[i] == '(' {
+					i = skipGroup(s, i)
+				} else if s[i] == '\'' {
+					i = skipQuoted(s, i)
+				} else {
+					i++
+				}
+			}
+			if i == start {
+				// A stray closing parenthesis
+				i++
+				continue
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+	return tokens
+}
+
+// Return the end of the quoted text starting at i, a doubled quote is escaped, and so is a quote after a backslash
+// in a string literal
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		if quote == '\'' && s[i] == '\\' {
+			i++
+		} else if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// Return the end of the parenthesized group starting at i, the parentheses in the quoted text are skipped
+func skipGroup(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '`', '"', '\'':
+			i = skipQuoted(s, i)
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return len(s)
+}
+
+// Split the tokens by the separator token
+func splitTokens(tokens []string, sep string) [][]string {
+	parts := make([][]string, 0)
+	start := 0
+	for i, token := range tokens {
+		if token == sep {
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// Remove the outer parentheses of a group
+func unwrapGroup(group string) string {
+	if strings.HasPrefix(group, "(") && strings.HasSuffix(group, ")") {
+		return group[1 : len(group)-1]
+	}
+	return group
+}
+
+func isQuotedIdent(token string) bool {
+	return strings.HasPrefix(token, "`") || strings.HasPrefix(token, `"`)
+}
+
+func unquoteIdent(token string) string {
+	if isQuotedIdent(token) && len(token) >= 2 {
+		quote := token[:1]
+		return strings.ReplaceAll(token[1:len(token)-1], quote+quote, quote)
+	}
+	return token
+}
+
+// Unquote a string literal escaped by the rules of MySQL, the reverse of escape
+func unquoteString(token string) string {
+	if !strings.HasPrefix(token, "'") || !strings.HasSuffix(token, "'") || len(token) < 2 {
+		return token
+	}
+	s := token[1 : len(token)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+			i++
+		} else if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case '0':
+				c = 0
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'Z':
+				c = '\032'
+			default:
+				c = s[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,399 @@
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type sqliteDialect struct{}
+
+// SQLite is the dialect for SQLite 3.35+, the column types are collapsed into the SQLite type affinities.
+// SQLite could not alter a column or the primary key of an existing table, such changes fail with ErrNotSupported.
+var SQLite Dialect = sqliteDialect{}
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (sqliteDialect) QuoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+// ReturningClause is empty, RETURNING requires SQLite 3.35+ and LastInsertId is used instead
+func (sqliteDialect) ReturningClause(columns []string) string {
+	return ""
+}
+
+func (sqliteDialect) InsertIgnore(query string) string {
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+func (sqliteDialect) ColumnType(field *Field) string {
+	name, _, _ := splitColumnType(field.Type)
+	switch {
+	case strings.Contains(name, "int") || name == "bit" || name == "bool" || name == "boolean":
+		return "INTEGER"
+	case name == "float" || name == "double" || name == "real":
+		return "REAL"
+	case name == "decimal" || name == "numeric":
+		return "NUMERIC"
+	case strings.Contains(name, "blob") || isSpatialType(name):
+		return "BLOB"
+	}
+	return "TEXT"
+}
+
+func (sqliteDialect) AutoIncrementClause(field *Field) string {
+	return " PRIMARY KEY AUTOINCREMENT"
+}
+
+func (sqliteDialect) TransactionalDDL() bool {
+	return true
+}
+
+func (d sqliteDialect) UpsertClause(keys []string, columns []string) string {
+	return onConflictClause(d, keys, columns)
+}
+
+func (d sqliteDialect) columnDefinition(field *Field) string {
+	sql := d.QuoteIdent(field.Name) + " " + d.ColumnType(field)
+	if field.Nullable {
+		sql += " NULL"
+	} else {
+		sql += " NOT NULL"
+	}
+	if field.AutoIncrement {
+		sql += d.AutoIncrementClause(field)
+	}
+	if field.DefaultValue != "" {
+		sql += " DEFAULT " + formatDefault(d, field)
+	}
+	return sql
+}
+
+func (d sqliteDialect) createIndex(sc *Schema, index *Index) string {
+	_, table := splitTableName(sc.Name)
+	sql := "CREATE INDEX IF NOT EXISTS "
+	if index.Unique {
+		sql = "CREATE UNIQUE INDEX IF NOT EXISTS "
+	}
+	return sql + quoteQualifiedName(d, sc.Name, index.Name) + " ON " + d.QuoteIdent(table) + " (" + quoteIndexColumns(d, index.Columns) + ")"
+}
+
+func (d sqliteDialect) CreateTable(sc *Schema) ([]string, error) {
+	if e := checkNullsDistinct(sc.Indices...); e != nil {
+		return nil, e
+	}
+	if e := checkPartitioning(sc); e != nil {
+		return nil, e
+	}
+	stmts := make([]string, 0, 1)
+	hasAI := false
+	sql := "CREATE TABLE IF NOT EXISTS " + quoteSchemaTable(d, sc.Name) + " ("
+	for i := range sc.Fields {
+		sql += d.columnDefinition(&sc.Fields[i]) + ","
+		hasAI = hasAI || sc.Fields[i].AutoIncrement
+	}
+	for i := range sc.Indices {
+		// The auto increment column is the primary key already
+		if sc.Indices[i].Primary && !hasAI {
+			sql += "PRIMARY KEY (" + quoteIndexColumns(d, sc.Indices[i].Columns) + "),"
+		}
+	}
+	for i := range sc.ForeignKeys {
+		sql += foreignKeyDefinition(d, sc, &sc.ForeignKeys[i]) + ","
+	}
+	for i := range sc.Checks {
+		sql += checkDefinition(d, sc, &sc.Checks[i]) + ","
+	}
+	stmts = append(stmts, sql[:len(sql)-1]+")")
+
+	for i := range sc.Indices {
+		if !sc.Indices[i].Primary {
+			stmts = append(stmts, d.createIndex(sc, &sc.Indices[i]))
+		}
+	}
+	return stmts, nil
+}
+
+// AlterTable does nothing, SQLite has no table options
+func (sqliteDialect) AlterTable(sc *Schema, cur *Schema) ([]string, error) {
+	return nil, checkPartitioning(sc)
+}
+
+func (d sqliteDialect) AddColumn(sc *Schema, field *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " ADD COLUMN " + d.columnDefinition(field)}, nil
+}
+
+func (d sqliteDialect) ModifyColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	if d.ColumnType(field) == cur.Type && field.Nullable == cur.Nullable && field.AutoIncrement == cur.AutoIncrement && normalizeDefault(field.DefaultValue) == normalizeDefault(cur.DefaultValue) {
+		// Only the comment differs, which is not stored by SQLite
+		return nil, nil
+	}
+	return nil, errors.Wrapf(ErrNotSupported, "Modify column %s", field.Name)
+}
+
+func (d sqliteDialect) RenameColumn(sc *Schema, field *Field, cur *Field) ([]string, error) {
+	stmts := []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " RENAME COLUMN " + d.QuoteIdent(cur.Name) + " TO " + d.QuoteIdent(field.Name)}
+	renamed := *cur
+	renamed.Name = field.Name
+	want := *field
+	want.Type = d.ColumnType(field)
+	if renamed.Equal(&want) {
+		return stmts, nil
+	}
+	modify, e := d.ModifyColumn(sc, field, &renamed)
+	return append(stmts, modify...), e
+}
+
+func (d sqliteDialect) DropColumn(sc *Schema, field *Field) ([]string, error) {
+	return []string{"ALTER TABLE " + quoteSchemaTable(d, sc.Name) + " DROP COLUMN " + d.QuoteIdent(field.Name)}, nil
+}
+
+func (d sqliteDialect) AddIndex(sc *Schema, index *Index) ([]string, error) {
+	if e := checkNullsDistinct(*index); e != nil {
+		return nil, e
+	}
+	if index.Primary {
+		return nil, errors.Wrap(ErrNotSupported, "Add primary key")
+	}
+	return []string{d.createIndex(sc, index)}, nil
+}
+
+func (d sqliteDialect) ModifyIndex(sc *Schema, index *Index, cur *Index) ([]string, error) {
+	if e := checkNullsDistinct(*index); e != nil {
+		return nil, e
+	}
+	if index.Primary {
+		return nil, errors.Wrap(ErrNotSupported, "Modify primary key")
+	}
+	return []string{"DROP INDEX IF EXISTS " + quoteQualifiedName(d, sc.Name, cur.Name), d.createIndex(sc, index)}, nil
+}
+
+func (d sqliteDialect) DropIndex(sc *Schema, index *Index) ([]string, error) {
+	if index.Primary {
+		return nil, errors.Wrap(ErrNotSupported, "Drop primary key")
+	}
+	return []string{"DROP INDEX IF EXISTS " + quoteQualifiedName(d, sc.Name, index.Name)}, nil
+}
+
+// AddForeignKey fails, the foreign keys could only be defined when the table is created
+func (sqliteDialect) AddForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return nil, errors.Wrap(ErrNotSupported, "Add foreign key")
+}
+
+// DropForeignKey fails, the foreign keys could only be defined when the table is created
+func (sqliteDialect) DropForeignKey(sc *Schema, fk *ForeignKey) ([]string, error) {
+	return nil, errors.Wrap(ErrNotSupported, "Drop foreign key")
+}
+
+// AddCheck fails, the checks could only be defined when the table is created
+func (sqliteDialect) AddCheck(sc *Schema, ck *Check) ([]string, error) {
+	return nil, errors.Wrap(ErrNotSupported, "Add check")
+}
+
+// DropCheck fails, the checks could only be defined when the table is created
+func (sqliteDialect) DropCheck(sc *Schema, ck *Check) ([]string, error) {
+	return nil, errors.Wrap(ErrNotSupported, "Drop check")
+}
+
+// ForeignKeyChecks enables the checks after the migration, SQLite does not keep the previous setting.
+// The pragma is ignored in a transaction, it's executed before the migration begins one.
+func (sqliteDialect) ForeignKeyChecks() ([]string, []string, error) {
+	return []string{"PRAGMA foreign_keys = OFF"}, []string{"PRAGMA foreign_keys = ON"}, nil
+}
+
+func (d sqliteDialect) ReadTable(db Executor, ctx context.Context, sc *Schema) (bool, error) {
+	_, table := splitTableName(sc.Name)
+	var name string
+	if e := db.QueryRowContext(ctx, "SELECT name FROM "+quoteQualifiedName(d, sc.Name, "sqlite_master")+" WHERE type = 'table' AND name = ?", table).Scan(&name); e != nil {
+		if e == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrap(e, "Get table info failed")
+	}
+	return true, nil
+}
+
+// A pragma statement on the table or index in the schema of the table, e.g. PRAGMA "main".table_info("users")
+func (d sqliteDialect) pragma(sc *Schema, pragma string, name string) string {
+	if schema, _ := splitTableName(sc.Name); schema != "" {
+		pragma = d.QuoteIdent(schema) + "." + pragma
+	}
+	return "PRAGMA " + pragma + "(" + d.QuoteIdent(name) + ")"
+}
+
+type sqliteColumn struct {
+	Field
+	pk int
+}
+
+func (d sqliteDialect) readColumns(db Executor, ctx context.Context, sc *Schema) ([]sqliteColumn, error) {
+	_, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, d.pragma(sc, "table_info", table))
+	if e != nil {
+		return nil, errors.Wrap(e, "Get table columns failed")
+	}
+	defer rows.Close()
+
+	columns := make([]sqliteColumn, 0)
+	for rows.Next() {
+		var column sqliteColumn
+		var cid, notNull int
+		var defaultValue sql.NullString
+		if e := rows.Scan(&cid, &column.Name, &column.Type, &notNull, &defaultValue, &column.pk); e != nil {
+			return nil, errors.Wrap(e, "Scan table columns failed")
+		}
+		column.Nullable = notNull == 0
+		column.DefaultValue = defaultValue.String
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func (d sqliteDialect) ReadColumns(db Executor, ctx context.Context, sc *Schema) error {
+	_, table := splitTableName(sc.Name)
+	var createSQL string
+	if e := db.QueryRowContext(ctx, "SELECT sql FROM "+quoteQualifiedName(d, sc.Name, "sqlite_master")+" WHERE type = 'table' AND name = ?", table).Scan(&createSQL); e != nil {
+		return errors.Wrap(e, "Get table info failed")
+	}
+
+	columns, e := d.readColumns(db, ctx, sc)
+	if e != nil {
+		return e
+	}
+
+	pks := 0
+	for _, column := range columns {
+		if column.pk > 0 {
+			pks++
+		}
+	}
+	for _, column := range columns {
+		// Only a single INTEGER PRIMARY KEY column could be declared as AUTOINCREMENT
+		if column.pk > 0 && pks == 1 && strings.EqualFold(column.Type, "INTEGER") && strings.Contains(strings.ToUpper(createSQL), "AUTOINCREMENT") {
+			column.AutoIncrement = true
+		}
+		sc.Fields = append(sc.Fields, column.Field)
+	}
+	return nil
+}
+
+func (d sqliteDialect) ReadIndices(db Executor, ctx context.Context, sc *Schema) error {
+	_, table := splitTableName(sc.Name)
+	columns, e := d.readColumns(db, ctx, sc)
+	if e != nil {
+		return e
+	}
+
+	primary := Index{Name: "PRIMARY", Primary: true, Columns: make([]string, 0, 1)}
+	for seq := 1; seq <= len(columns); seq++ {
+		for _, column := range columns {
+			if column.pk == seq {
+				primary.Columns = append(primary.Columns, column.Name)
+			}
+		}
+	}
+	if len(primary.Columns) > 0 {
+		sc.Indices = append(sc.Indices, primary)
+	}
+
+	rows, e := db.QueryContext(ctx, d.pragma(sc, "index_list", table))
+	if e != nil {
+		return errors.Wrap(e, "Get table indices failed")
+	}
+	indices := make([]Index, 0)
+	for rows.Next() {
+		var index Index
+		var seq, unique, partial int
+		var origin string
+		if e := rows.Scan(&seq, &index.Name, &unique, &origin, &partial); e != nil {
+			rows.Close()
+			return errors.Wrap(e, "Scan table indices failed")
+		}
+		// The primary key has been read from the table info
+		if origin == "pk" {
+			continue
+		}
+		index.Unique = unique != 0
+		indices = append(indices, index)
+	}
+	rows.Close()
+	if e := rows.Err(); e != nil {
+		return errors.Wrap(e, "Get table indices failed")
+	}
+
+	for _, index := range indices {
+		// The extended index info has the direction of the columns, the auxiliary columns like the rowid are not keys
+		rows, e := db.QueryContext(ctx, d.pragma(sc, "index_xinfo", index.Name))
+		if e != nil {
+			return errors.Wrap(e, "Get index columns failed")
+		}
+		index.Columns = make([]string, 0, 1)
+		for rows.Next() {
+			var seq, cid, desc, key int
+			var column, collation sql.NullString
+			if e := rows.Scan(&seq, &cid, &column, &desc, &collation, &key); e != nil {
+				rows.Close()
+				return errors.Wrap(e, "Scan index columns failed")
+			}
+			if key == 0 {
+				continue
+			}
+			if desc != 0 {
+				column.String += " DESC"
+			}
+			index.Columns = append(index.Columns, column.String)
+		}
+		rows.Close()
+		sc.Indices = append(sc.Indices, index)
+	}
+
+	return nil
+}
+
+// SQLite does not keep the constraint names, the foreign keys are named by the default naming rule
+func (d sqliteDialect) ReadForeignKeys(db Executor, ctx context.Context, sc *Schema) error {
+	_, table := splitTableName(sc.Name)
+	rows, e := db.QueryContext(ctx, d.pragma(sc, "foreign_key_list", table))
+	if e != nil {
+		return errors.Wrap(e, "Get table foreign keys failed")
+	}
+	defer rows.Close()
+
+	lastID := -1
+	for rows.Next() {
+		var id, seq int
+		var refTable, column, refColumn, onUpdate, onDelete, match string
+		if e := rows.Scan(&id, &seq, &refTable, &column, &refColumn, &onUpdate, &onDelete, &match); e != nil {
+			return errors.Wrap(e, "Scan table foreign keys failed")
+		}
+		if id == lastID {
+			n := len(sc.ForeignKeys) - 1
+			sc.ForeignKeys[n].Columns = append(sc.ForeignKeys[n].Columns, column)
+			sc.ForeignKeys[n].RefColumns = append(sc.ForeignKeys[n].RefColumns, refColumn)
+		} else {
+			sc.ForeignKeys = append(sc.ForeignKeys, ForeignKey{Columns: []string{column}, RefTable: refTable, RefColumns: []string{refColumn}, OnDelete: onDelete, OnUpdate: onUpdate})
+		}
+		lastID = id
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	for i := range sc.ForeignKeys {
+		sc.ForeignKeys[i].Name = sc.ForeignKeyName(&sc.ForeignKeys[i])
+	}
+	return nil
+}
+
+// ReadChecks returns ErrNotSupported, SQLite only keeps the checks in the CREATE TABLE statement
+func (sqliteDialect) ReadChecks(db Executor, ctx context.Context, sc *Schema) error {
+	return errors.Wrap(ErrNotSupported, "Read checks")
+}
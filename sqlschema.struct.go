@@ -1,34 +1,99 @@
 package sqlschema
 
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+)
+
+// Executor is the subset of *sql.DB used by this package, *sql.Tx and *sql.Conn satisfy it as well,
+// so the functions could run inside a transaction or on a pinned connection.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Field struct {
 	Name          string
 	Type          string
 	Nullable      bool
 	AutoIncrement bool
-	DefaultValue  string
+	DefaultValue  string // NULL for an explicit DEFAULT NULL, which is compared equal to no default
+	OnUpdate      string // ON UPDATE expression, e.g. CURRENT_TIMESTAMP
+	Charset       string // Character set of the column, the table default is used if empty
+	Collate       string // Collation of the column, the table default is used if empty
 	Comment       string
 }
 
 type Index struct {
 	Name    string
-	Columns []string
+	Columns []string // Column names, optionally followed by the prefix length and the direction, e.g. name(10), time DESC
 	Primary bool
 	Unique  bool
+	Type    string // BTREE | HASH | FULLTEXT | SPATIAL, the default BTREE is used if empty
+	// NullsNotDistinct makes a unique index treat NULL values as equal, so only one row could have NULL.
+	// It's supported by PostgreSQL 15+ only, the other dialects allow any number of NULL values in a unique
+	// index and refuse the index with ErrNotSupported.
+	NullsNotDistinct bool
+	Comment          string // Comment of the index, SQLite does not keep it
+}
+
+type ForeignKey struct {
+	Name       string // Name of the constraint, fk_<table>_<columns> will be used if empty
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   string // RESTRICT | CASCADE | SET NULL | NO ACTION
+	OnUpdate   string // RESTRICT | CASCADE | SET NULL | NO ACTION
+}
+
+// Check is a CHECK constraint, it's supported by MySQL 8.0.16+, PostgreSQL and SQLite
+type Check struct {
+	Name       string // Name of the constraint, chk_<table>_<column> will be used if empty
+	Column     string // The column declaring the constraint by the check option of the tag
+	Expression string // e.g. age >= 0
+}
+
+// Partitioning describes how a MySQL table is partitioned, the subpartitions are not supported
+type Partitioning struct {
+	Method     string      // RANGE | RANGE COLUMNS | LIST | LIST COLUMNS | HASH | LINEAR HASH | KEY | LINEAR KEY
+	Expression string      // The expression or the columns partitioned by, e.g. YEAR(created)
+	Partitions []Partition // The partitions of RANGE and LIST
+	Count      int         // The number of partitions of HASH and KEY, 1 if 0
+}
+
+type Partition struct {
+	Name   string
+	Values string // The values of the partition without VALUES LESS THAN or VALUES IN, e.g. 2024, MAXVALUE or 1,2,3
 }
 
 type Schema struct {
-	Name    string
-	Fields  []Field
-	Indices []Index
-	Engine  string
-	Collate string
-	Comment string
+	Name        string // Name of the table, could be in the form of schema.table
+	Fields      []Field
+	Indices     []Index
+	ForeignKeys []ForeignKey
+	Checks      []Check
+	Engine      string
+	Collate     string
+	Comment     string
+	RowFormat   string // DYNAMIC | COMPRESSED | COMPACT | REDUNDANT, MySQL only, the server default is used if empty
+	// AutoIncrement is the next value of the auto increment counter, MySQL only, the counter is kept if 0.
+	// It's only raised by an update, MySQL ignores a value not greater than the maximum of the column.
+	AutoIncrement int64
+	// Partitioning of the table, MySQL only. A table is repartitioned by an update if it differs, except the
+	// partitions appended to a RANGE or LIST partitioning which are added. It's kept as is if nil.
+	Partitioning *Partitioning
+	Dialect      Dialect // The dialect used to create and update the table, DefaultDialect will be used if nil
+
+	checksUnknown bool // The server does not report the checks, they are not compared
 }
 
 func (sc *Schema) Field(name string) *Field {
-	for _, field := range sc.Fields {
-		if field.Name == name {
-			return &field
+	for i := range sc.Fields {
+		if sc.Fields[i].Name == name {
+			return &sc.Fields[i]
 		}
 	}
 	return nil
@@ -38,9 +103,45 @@ func (sc *Schema) Index(name string) *Index {
 	if name == "PRIMARY" {
 		name = ""
 	}
-	for _, index := range sc.Indices {
-		if index.Name == name || (name == "" && index.Primary) {
-			return &index
+	for i := range sc.Indices {
+		if sc.Indices[i].Name == name || (name == "" && sc.Indices[i].Primary) {
+			return &sc.Indices[i]
+		}
+	}
+	return nil
+}
+
+// ForeignKeyName returns the name of the foreign key constraint, generated from the table and columns if not set
+func (sc *Schema) ForeignKeyName(fk *ForeignKey) string {
+	if fk.Name != "" {
+		return fk.Name
+	}
+	_, table := splitTableName(sc.Name)
+	return "fk_" + table + "_" + strings.Join(fk.Columns, "_")
+}
+
+func (sc *Schema) ForeignKey(name string) *ForeignKey {
+	for i := range sc.ForeignKeys {
+		if sc.ForeignKeyName(&sc.ForeignKeys[i]) == name {
+			return &sc.ForeignKeys[i]
+		}
+	}
+	return nil
+}
+
+// CheckName returns the name of the check constraint, generated from the table and column if not set
+func (sc *Schema) CheckName(ck *Check) string {
+	if ck.Name != "" {
+		return ck.Name
+	}
+	_, table := splitTableName(sc.Name)
+	return "chk_" + table + "_" + ck.Column
+}
+
+func (sc *Schema) Check(name string) *Check {
+	for i := range sc.Checks {
+		if sc.CheckName(&sc.Checks[i]) == name {
+			return &sc.Checks[i]
 		}
 	}
 	return nil
@@ -50,7 +151,7 @@ func (fd *Field) Equal(other *Field) bool {
 	if fd.Name != other.Name {
 		return false
 	}
-	if fd.Type != other.Type {
+	if normalizeType(fd.Type) != normalizeType(other.Type) {
 		return false
 	}
 	if fd.Nullable != other.Nullable {
@@ -59,15 +160,18 @@ func (fd *Field) Equal(other *Field) bool {
 	if fd.AutoIncrement != other.AutoIncrement {
 		return false
 	}
-	defVal1 := fd.DefaultValue
-	defVal2 := other.DefaultValue
-	if defVal1 == "NULL" {
-		defVal1 = ""
+	if normalizeDefault(fd.DefaultValue) != normalizeDefault(other.DefaultValue) {
+		return false
 	}
-	if defVal2 == "NULL" {
-		defVal2 = ""
+	if normalizeDefault(fd.OnUpdate) != normalizeDefault(other.OnUpdate) {
+		return false
 	}
-	if defVal1 != defVal2 {
+	// The charset and collation are only compared when both sides have them, the server reports the
+	// table default for the columns without an explicit one
+	if fd.Charset != "" && other.Charset != "" && !strings.EqualFold(fd.Charset, other.Charset) {
+		return false
+	}
+	if fd.Collate != "" && other.Collate != "" && !strings.EqualFold(fd.Collate, other.Collate) {
 		return false
 	}
 	if fd.Comment != other.Comment {
@@ -76,6 +180,57 @@ func (fd *Field) Equal(other *Field) bool {
 	return true
 }
 
+// Normalize a column type for comparison, the type is compared case-insensitively except the quoted values
+// of enum and set. MySQL 8 drops the display width of the integer types, it's only kept for tinyint(1) and
+// zerofill columns.
+func normalizeType(t string) string {
+	name, param, modifiers := splitColumnType(t)
+	modifiers = strings.ToLower(modifiers)
+	if !strings.Contains(param, "'") {
+		param = strings.ToLower(strings.ReplaceAll(param, " ", ""))
+	}
+	// MySQL reports boolean as tinyint(1)
+	if name == "boolean" || name == "bool" {
+		name, param = "tinyint", "1"
+	}
+	switch name {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if !(name == "tinyint" && param == "1") && !strings.Contains(modifiers, "zerofill") {
+			param = ""
+		}
+	}
+	if param != "" {
+		name += "(" + param + ")"
+	}
+	if modifiers != "" {
+		name += " " + modifiers
+	}
+	return name
+}
+
+// Normalize a default value for comparison, quoted literals are unquoted and expressions are upper cased,
+// as servers report the default value either quoted or not. TRUE and FALSE are the same as 1 and 0, MySQL
+// reports them as numbers and PostgreSQL reports the defaults of the boolean columns as keywords.
+func normalizeDefault(v string) string {
+	if strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") && len(v) >= 2 {
+		v = v[1 : len(v)-1]
+		v = strings.ReplaceAll(v, "''", "'")
+		return strings.ReplaceAll(v, "\\'", "'")
+	}
+	if isDefaultExpression(v) {
+		v = strings.TrimSuffix(strings.ToUpper(v), "()")
+		switch v {
+		case "NULL":
+			return ""
+		case "TRUE":
+			return "1"
+		case "FALSE":
+			return "0"
+		}
+	}
+	return v
+}
+
 func (idx *Index) Equal(other *Index) bool {
 	if idx.Primary != other.Primary {
 		return false
@@ -86,6 +241,15 @@ func (idx *Index) Equal(other *Index) bool {
 	if idx.Unique != other.Unique {
 		return false
 	}
+	if normalizeIndexType(idx.Type) != normalizeIndexType(other.Type) {
+		return false
+	}
+	if idx.NullsNotDistinct != other.NullsNotDistinct {
+		return false
+	}
+	if idx.Comment != other.Comment {
+		return false
+	}
 	if len(idx.Columns) != len(other.Columns) {
 		return false
 	}
@@ -96,3 +260,119 @@ func (idx *Index) Equal(other *Index) bool {
 	}
 	return true
 }
+
+// BTREE is the default index type of all the dialects
+func normalizeIndexType(t string) string {
+	t = strings.ToUpper(t)
+	if t == "BTREE" {
+		return ""
+	}
+	return t
+}
+
+// RESTRICT and NO ACTION are the same and the default for most servers
+func normalizeReferentialAction(action string) string {
+	action = strings.ToUpper(strings.TrimSpace(action))
+	if action == "RESTRICT" || action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+// The expressions are compared without the spaces, quotes and parentheses, the servers report them rewritten,
+// e.g. MySQL reports age >= 0 as (`age` >= 0)
+func (ck *Check) Equal(other *Check) bool {
+	return normalizeCheck(ck.Expression) == normalizeCheck(other.Expression)
+}
+
+// Normalize a check expression for comparison, the string literals are kept as is
+func normalizeCheck(expr string) string {
+	var b strings.Builder
+	quoted := false
+	for _, c := range expr {
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if !quoted && strings.ContainsRune(" \t\n\r`\"()", c) {
+			continue
+		}
+		if !quoted {
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// The expression and the values are compared like the check expressions, the servers report them rewritten
+func (p *Partitioning) Equal(other *Partitioning) bool {
+	if normalizePartitionMethod(p.Method) != normalizePartitionMethod(other.Method) {
+		return false
+	}
+	if normalizeCheck(p.Expression) != normalizeCheck(other.Expression) {
+		return false
+	}
+	if p.partitionCount() != other.partitionCount() {
+		return false
+	}
+	if len(p.Partitions) != len(other.Partitions) {
+		return false
+	}
+	for i := range p.Partitions {
+		if !p.Partitions[i].Equal(&other.Partitions[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// The number of partitions of HASH and KEY, 0 for RANGE and LIST
+func (p *Partitioning) partitionCount() int {
+	if !isHashPartitioning(p.Method) {
+		return 0
+	}
+	if p.Count <= 0 {
+		return 1
+	}
+	return p.Count
+}
+
+func (part *Partition) Equal(other *Partition) bool {
+	return part.Name == other.Name && normalizeCheck(part.Values) == normalizeCheck(other.Values)
+}
+
+func normalizePartitionMethod(method string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(method), " "))
+}
+
+// HASH and KEY partitionings have a number of partitions instead of their definitions
+func isHashPartitioning(method string) bool {
+	method = normalizePartitionMethod(method)
+	return strings.HasSuffix(method, "HASH") || strings.HasSuffix(method, "KEY")
+}
+
+func (fk *ForeignKey) Equal(other *ForeignKey) bool {
+	if fk.RefTable != other.RefTable {
+		return false
+	}
+	if len(fk.Columns) != len(other.Columns) || len(fk.RefColumns) != len(other.RefColumns) {
+		return false
+	}
+	for i, column := range fk.Columns {
+		if column != other.Columns[i] {
+			return false
+		}
+	}
+	for i, column := range fk.RefColumns {
+		if column != other.RefColumns[i] {
+			return false
+		}
+	}
+	if normalizeReferentialAction(fk.OnDelete) != normalizeReferentialAction(other.OnDelete) {
+		return false
+	}
+	if normalizeReferentialAction(fk.OnUpdate) != normalizeReferentialAction(other.OnUpdate) {
+		return false
+	}
+	return true
+}
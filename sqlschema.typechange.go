@@ -0,0 +1,228 @@
+package sqlschema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TypeChange classifies the change of a column type by whether the values of the column survive it
+type TypeChange int
+
+const (
+	TypeUnchanged    TypeChange = iota // The type is the same, only the other attributes of the column changed
+	TypeWidening                       // Every value of the old type fits into the new type, e.g. int to bigint
+	TypeNarrowing                      // Values may be truncated or out of range, e.g. varchar(255) to varchar(64)
+	TypeIncompatible                   // The types are of different kinds, e.g. varchar to int
+)
+
+func (tc TypeChange) String() string {
+	switch tc {
+	case TypeUnchanged:
+		return "unchanged"
+	case TypeWidening:
+		return "widening"
+	case TypeNarrowing:
+		return "narrowing"
+	}
+	return "incompatible"
+}
+
+// The parsed type of a column, the types of PostgreSQL and SQLite are mapped to the MySQL ones
+type columnTypeInfo struct {
+	kind     string // integer, bit, decimal, float, string, binary, enum, set, temporal or the name of the type
+	name     string
+	size     int64 // Bytes of an integer or a float, bits of a bit, length of a string, precision of a decimal, fsp of a temporal
+	scale    int64 // Scale of a decimal
+	unsigned bool
+	values   []string // Values of an enum or a set
+}
+
+var integerSizes = map[string]int64{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 8}
+
+// The largest length of the unbounded types, e.g. text of PostgreSQL
+const unboundedLength = 1<<32 - 1
+
+var textLengths = map[string]int64{
+	"tinytext": 1<<8 - 1, "text": 1<<16 - 1, "mediumtext": 1<<24 - 1, "longtext": unboundedLength,
+	"tinyblob": 1<<8 - 1, "blob": 1<<16 - 1, "mediumblob": 1<<24 - 1, "longblob": unboundedLength,
+}
+
+// The aliases used by PostgreSQL and SQLite
+var typeAliases = map[string]string{
+	"character varying": "varchar", "character": "char", "bpchar": "char", "int2": "smallint", "int4": "int",
+	"int8": "bigint", "double precision": "double", "float8": "double", "float4": "float", "numeric": "decimal",
+	"bytea": "longblob", "timestamp without time zone": "datetime", "timestamp with time zone": "datetime",
+	"timestamptz": "datetime", "time without time zone": "time", "bool": "boolean",
+}
+
+func parseColumnTypeInfo(t string) columnTypeInfo {
+	t = strings.ToLower(strings.TrimSpace(t))
+	name, param, modifiers := t, "", ""
+	if i := strings.Index(t, "("); i >= 0 {
+		name = strings.TrimSpace(t[:i])
+		if j := strings.LastIndex(t, ")"); j > i {
+			param, modifiers = t[i+1:j], t[j+1:]
+		}
+	}
+	// PostgreSQL places the modifiers after the parameter, e.g. timestamp(3) without time zone
+	if rest := strings.TrimSpace(modifiers); strings.HasSuffix(rest, "time zone") {
+		name += " " + rest
+		modifiers = ""
+	}
+	name = strings.Join(strings.Fields(name), " ")
+	for _, m := range []string{"unsigned", "zerofill"} {
+		if strings.HasSuffix(name, " "+m) {
+			name = strings.TrimSuffix(name, " "+m)
+			modifiers += " " + m
+		}
+	}
+	// The timestamps of PostgreSQL have the precision of microseconds by default
+	fsp := int64(0)
+	if strings.HasSuffix(name, "time zone") || name == "timestamptz" {
+		fsp = 6
+	}
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+	info := columnTypeInfo{kind: name, name: name, unsigned: strings.Contains(modifiers, "unsigned")}
+	params := strings.Split(strings.ReplaceAll(param, " ", ""), ",")
+	number := func(i int, def int64) int64 {
+		if i < len(params) {
+			if n, e := strconv.ParseInt(params[i], 10, 64); e == nil {
+				return n
+			}
+		}
+		return def
+	}
+	switch {
+	case name == "tinyint" && param == "1", name == "boolean":
+		info.kind, info.name, info.size = "integer", "tinyint", 1
+	case integerSizes[name] > 0:
+		info.kind, info.size = "integer", integerSizes[name]
+	case name == "bit":
+		info.kind, info.size = "bit", number(0, 1)
+	case name == "decimal":
+		info.kind, info.size, info.scale = "decimal", number(0, 10), number(1, 0)
+	case name == "float" || name == "real" || name == "double":
+		info.kind, info.size = "float", 8
+		if name == "float" && number(0, 0) <= 24 || name == "real" {
+			info.size = 4
+		}
+	case name == "char" || name == "varchar":
+		info.kind, info.size = "string", number(0, 1)
+		if name == "varchar" && param == "" {
+			info.size = unboundedLength
+		}
+	case name == "binary" || name == "varbinary":
+		info.kind, info.size = "binary", number(0, 1)
+	case strings.HasSuffix(name, "text"), name == "clob":
+		info.kind, info.size = "string", textLengths[name]
+		if info.size == 0 {
+			info.size = unboundedLength
+		}
+	case strings.HasSuffix(name, "blob"):
+		info.kind, info.size = "binary", textLengths[name]
+	case name == "enum" || name == "set":
+		info.values = enumTypeValues(param)
+	case name == "date" || name == "datetime" || name == "timestamp" || name == "time" || name == "year":
+		info.kind, info.size = "temporal", number(0, fsp)
+	}
+	return info
+}
+
+// Split the values of an enum or a set, e.g. 'a','b'
+func enumTypeValues(param string) []string {
+	values := make([]string, 0)
+	for _, token := range createTableTokens(param) {
+		if token != "," {
+			values = append(values, unquoteString(token))
+		}
+	}
+	return values
+}
+
+// The number of decimal digits of the largest value of an integer type
+func integerDigits(info *columnTypeInfo) int64 {
+	digits := map[int64]int64{1: 3, 2: 5, 3: 8, 4: 10, 8: 19}[info.size]
+	if info.unsigned && info.size == 8 {
+		digits = 20
+	}
+	return digits
+}
+
+// Classify the change of a column type from the one read from the database to the one defined, both are in the
+// syntax of the dialect. The character sets are not considered, the lengths are compared in characters.
+func classifyTypeChange(from string, to string) TypeChange {
+	if normalizeType(from) == normalizeType(to) {
+		return TypeUnchanged
+	}
+	a, b := parseColumnTypeInfo(from), parseColumnTypeInfo(to)
+	widening := func(ok bool) TypeChange {
+		if ok {
+			return TypeWidening
+		}
+		return TypeNarrowing
+	}
+	// The negative values are lost by an unsigned type
+	keepsSign := a.unsigned || !b.unsigned
+	switch {
+	case a.kind == "integer" && b.kind == "integer":
+		if a.unsigned == b.unsigned {
+			return widening(b.size >= a.size)
+		}
+		return widening(a.unsigned && b.size > a.size)
+	case a.kind == "integer" && b.kind == "decimal":
+		return widening(keepsSign && b.size-b.scale >= integerDigits(&a))
+	case a.kind == "integer" && b.kind == "float":
+		// A double is exact up to 2^53 and a float up to 2^24
+		return widening(keepsSign && (b.size == 8 && a.size <= 4 || a.size <= 2))
+	case a.kind == "decimal" && b.kind == "decimal":
+		return widening(keepsSign && b.size-b.scale >= a.size-a.scale && b.scale >= a.scale)
+	case a.kind == "float" && b.kind == "float":
+		return widening(keepsSign && b.size >= a.size)
+	case a.kind == "bit" && b.kind == "bit":
+		return widening(b.size >= a.size)
+	case a.kind == "string" && b.kind == "string", a.kind == "binary" && b.kind == "binary":
+		return widening(b.size >= a.size)
+	case a.kind == b.kind && (a.kind == "enum" || a.kind == "set"):
+		return widening(containsAll(b.values, a.values))
+	case (a.kind == "enum" || a.kind == "set") && b.kind == "string":
+		// A set value is the values joined by commas
+		longest := int64(0)
+		for _, v := range a.values {
+			if a.kind == "set" {
+				longest += int64(len([]rune(v))) + 1
+			} else if n := int64(len([]rune(v))); n > longest {
+				longest = n
+			}
+		}
+		return widening(b.size >= longest)
+	case a.kind == "temporal" && b.kind == "temporal":
+		switch {
+		case a.name == b.name:
+			return widening(b.size >= a.size)
+		case b.name == "datetime" && (a.name == "date" || a.name == "timestamp"):
+			return widening(b.size >= a.size)
+		case a.name == "time" || b.name == "time" || a.name == "year" || b.name == "year":
+			return TypeIncompatible
+		}
+		// A date or datetime may be out of the range of a timestamp, the time of a datetime is lost by a date
+		return TypeNarrowing
+	case isNumericKind(a.kind) && isNumericKind(b.kind):
+		return TypeNarrowing
+	}
+	return TypeIncompatible
+}
+
+func isNumericKind(kind string) bool {
+	return kind == "integer" || kind == "decimal" || kind == "float" || kind == "bit"
+}
+
+func containsAll(values []string, subset []string) bool {
+	for _, v := range subset {
+		if !containsString(values, v) {
+			return false
+		}
+	}
+	return true
+}
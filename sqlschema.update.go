@@ -3,122 +3,177 @@ package sqlschema
 import (
 	"context"
 	"database/sql"
+
+	"github.com/pkg/errors"
 )
 
-func (sc *Schema) Update(db *sql.DB, ctx context.Context) error {
-	cur, e := ReadFromDB(db, ctx, sc.Name)
-	if e != nil {
-		return e
-	}
+// UpdateOptions changes the way Schema.Update migrates the table
+type UpdateOptions struct {
+	// Renames maps the old column names to the new ones, a renamed column is changed in place
+	// instead of being dropped and added, which would lose its data.
+	Renames map[string]string
+	// SafeMode refuses to drop the columns and indices missing from the schema, and to narrow the types of the columns,
+	// they are reported in the RefusedColumns, RefusedIndices and RefusedModifications of the diff instead.
+	SafeMode bool
+	// ShowCreate reads the current schema by SHOW CREATE TABLE instead of information_schema, see ReadFromDBCreate.
+	ShowCreate bool
+	// DisableForeignKeyChecks runs the migration with the foreign key checks disabled, e.g. SET FOREIGN_KEY_CHECKS=0
+	// on MySQL. A *sql.DB is pinned to one of its connections, so the setting applies to all the statements, and the
+	// setting is restored afterwards. PostgreSQL returns ErrNotSupported.
+	DisableForeignKeyChecks bool
+}
+
+// Update creates the table or migrates it to the schema. The statements are executed one by one and the
+// migration stops when ctx is done. If the dialect supports transactional DDL (PostgreSQL and SQLite) and db could
+// begin a transaction (*sql.DB and *sql.Conn), all the statements are executed in a transaction, so the migration
+// is atomic. MySQL commits every DDL statement implicitly, a failed migration leaves the statements before it applied.
+func (sc *Schema) Update(db Executor, ctx context.Context) error {
+	_, e := sc.UpdateWithOptions(db, ctx, nil)
+	return e
+}
 
-	if cur == nil {
-		return sc.Create(db, ctx)
+// UpdateWithOptions is Update with options, opts could be nil. It returns the changes applied to the table.
+func (sc *Schema) UpdateWithOptions(db Executor, ctx context.Context, opts *UpdateOptions) (*SchemaDiff, error) {
+	if opts != nil && opts.DisableForeignKeyChecks {
+		inner := *opts
+		inner.DisableForeignKeyChecks = false
+		var diff *SchemaDiff
+		e := withoutForeignKeyChecks(db, ctx, sc.dialect(), func(db Executor) error {
+			var e error
+			diff, e = sc.UpdateWithOptions(db, ctx, &inner)
+			return e
+		})
+		return diff, e
 	}
 
-	sql := ""
-	args := make([]interface{}, 0, 10)
+	diff, e := sc.DiffWithOptions(db, ctx, opts)
+	if e != nil {
+		return nil, e
+	}
 
-	if sc.Engine != cur.Engine {
-		sql += " ENGINE = " + sc.Engine
+	stmts, e := diff.Statements()
+	if e != nil {
+		return nil, e
 	}
 
-	if sc.Collate != cur.Collate {
-		sql += " COLLATE = " + sc.Collate
+	if beginner, ok := db.(txBeginner); ok && sc.dialect().TransactionalDDL() && len(stmts) > 1 {
+		tx, e := beginner.BeginTx(ctx, nil)
+		if e != nil {
+			return nil, errors.Wrap(e, "Begin transaction failed")
+		}
+		if e := execStatements(tx, ctx, stmts); e != nil {
+			tx.Rollback()
+			return nil, e
+		}
+		if e := tx.Commit(); e != nil {
+			return nil, errors.Wrap(e, "Commit transaction failed")
+		}
+		return diff, nil
 	}
 
-	if sc.Comment != cur.Comment {
-		sql += " COMMENT = '" + escape(sc.Comment) + "'"
+	if e := execStatements(db, ctx, stmts); e != nil {
+		return nil, e
 	}
+	return diff, nil
+}
+
+// txBeginner is satisfied by *sql.DB and *sql.Conn
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
 
-	if sql != "" {
-		sql = "ALTER TABLE `" + sc.Name + "`" + sql
-		_, e = db.ExecContext(ctx, sql, args...)
+// connPinner is satisfied by *sql.DB
+type connPinner interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// Run fn with the foreign key checks of the session disabled. A pool is pinned to one of its connections,
+// *sql.Conn and *sql.Tx run on a single connection already.
+func withoutForeignKeyChecks(db Executor, ctx context.Context, d Dialect, fn func(db Executor) error) error {
+	disable, restore, e := d.ForeignKeyChecks()
+	if e != nil {
+		return e
+	}
+	if pool, ok := db.(connPinner); ok {
+		conn, e := pool.Conn(ctx)
 		if e != nil {
-			return e
+			return errors.Wrap(e, "Get connection failed")
 		}
+		defer conn.Close()
+		db = conn
 	}
 
-	for _, field := range cur.Fields {
-		if sc.Field(field.Name) == nil {
-			sql = "ALTER TABLE `" + sc.Name + "` DROP `" + field.Name + "`"
-			_, e = db.ExecContext(ctx, sql, args...)
-			if e != nil {
-				return e
-			}
+	if e := execStatements(db, ctx, disable); e != nil {
+		return e
+	}
+	e = fn(db)
+	// The setting is restored even if ctx is done, the connection goes back to the pool
+	for _, stmt := range restore {
+		if _, re := execLogged(context.Background(), db.ExecContext, stmt); re != nil && e == nil {
+			e = re
 		}
 	}
+	return e
+}
 
-	for _, field := range sc.Fields {
-		fd := cur.Field(field.Name)
-		sql = ""
-		if fd == nil {
-			sql = "ALTER TABLE `" + sc.Name + "` ADD `" + field.Name + "` " + field.Type
-		} else if !fd.Equal(&field) {
-			sql = "ALTER TABLE `" + sc.Name + "` MODIFY `" + field.Name + "` " + field.Type
+// Execute the statements in order, stop before the next statement once ctx is done
+func execStatements(db Executor, ctx context.Context, stmts []string) error {
+	for _, stmt := range stmts {
+		if e := ctx.Err(); e != nil {
+			return errors.Wrap(e, "Update aborted")
 		}
-		if sql != "" {
-			if field.Nullable {
-				sql += " NULL"
-			} else {
-				sql += " NOT NULL"
-			}
-			if field.AutoIncrement {
-				sql += " AUTO_INCREMENT"
-			}
-			if field.DefaultValue != "" {
-				sql += " DEFAULT " + field.DefaultValue
-			}
-			if field.Comment != "" {
-				sql += " COMMENT '" + escape(field.Comment) + "'"
-			}
-			_, e = db.ExecContext(ctx, sql, args...)
-			if e != nil {
-				return e
-			}
+		if _, e := execLogged(ctx, db.ExecContext, stmt); e != nil {
+			return e
 		}
 	}
+	return nil
+}
 
-	for _, index := range cur.Indices {
-		if sc.Index(index.Name) == nil {
-			sql = "ALTER TABLE `" + sc.Name + "` DROP INDEX `" + index.Name + "`"
-			_, e = db.ExecContext(ctx, sql, args...)
-			if e != nil {
-				return e
-			}
-		}
+// UpdateSQL returns the statements Update would execute without executing them,
+// the current schema is still read from the database to compute the difference.
+func (sc *Schema) UpdateSQL(db Executor, ctx context.Context) ([]string, error) {
+	return sc.UpdateSQLWithOptions(db, ctx, nil)
+}
+
+// UpdateSQLWithOptions is UpdateSQL with options, opts could be nil
+func (sc *Schema) UpdateSQLWithOptions(db Executor, ctx context.Context, opts *UpdateOptions) ([]string, error) {
+	diff, e := sc.DiffWithOptions(db, ctx, opts)
+	if e != nil {
+		return nil, e
 	}
+	return diff.Statements()
+}
 
-	for _, index := range sc.Indices {
-		idx := cur.Index(index.Name)
-		sql = ""
-		if idx == nil {
-			if index.Primary {
-				sql = "ALTER TABLE `" + sc.Name + "` ADD PRIMARY KEY ("
-			} else if index.Unique {
-				sql = "ALTER TABLE `" + sc.Name + "` ADD UNIQUE KEY `" + index.Name + "` ("
-			} else {
-				sql = "ALTER TABLE `" + sc.Name + "` ADD KEY `" + index.Name + "` ("
-			}
-		} else if !idx.Equal(&index) {
-			if index.Primary {
-				sql = "ALTER TABLE `" + sc.Name + "` DROP PRIMARY KEY, ADD PRIMARY KEY ("
-			} else if index.Unique {
-				sql = "ALTER TABLE `" + sc.Name + "` DROP INDEX `" + index.Name + "`, ADD UNIQUE KEY `" + index.Name + "` ("
-			} else {
-				sql = "ALTER TABLE `" + sc.Name + "` DROP INDEX `" + index.Name + "`, ADD KEY `" + index.Name + "` ("
-			}
-		}
-		if sql != "" {
-			for _, column := range index.Columns {
-				sql += "`" + column + "`,"
-			}
-			sql = sql[:len(sql)-1] + ")"
-			_, e = db.ExecContext(ctx, sql, args...)
-			if e != nil {
-				return e
-			}
-		}
+// Diff reads the current schema of the table and returns the changes Update would apply, nothing is executed
+func (sc *Schema) Diff(db Executor, ctx context.Context) (*SchemaDiff, error) {
+	return sc.DiffWithOptions(db, ctx, nil)
+}
+
+// IsInSync reports whether the table matches the schema, i.e. Update would change nothing. The types, default
+// values and checks are compared normalized, so the forms rewritten by the server are not reported as drifts.
+func (sc *Schema) IsInSync(db Executor, ctx context.Context) (bool, error) {
+	diff, e := sc.Diff(db, ctx)
+	if e != nil {
+		return false, e
 	}
+	return diff.Empty(), nil
+}
 
-	return nil
+// DiffWithOptions is Diff with options, opts could be nil
+func (sc *Schema) DiffWithOptions(db Executor, ctx context.Context, opts *UpdateOptions) (*SchemaDiff, error) {
+	if opts == nil {
+		opts = &UpdateOptions{}
+	}
+
+	d := sc.dialect()
+	read := ReadFromDBWithDialect
+	if opts.ShowCreate {
+		read = readFromDBCreate
+	}
+	cur, e := read(db, ctx, d, sc.Name)
+	if e != nil {
+		return nil, e
+	}
+
+	return sc.compare(d, cur, opts)
 }
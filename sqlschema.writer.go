@@ -0,0 +1,174 @@
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// execFunc executes a statement, it's the ExecContext of an Executor or a Writer
+type execFunc func(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+// queryRowFunc runs a query returning at most one row, it's the QueryRowContext of an Executor or a Writer
+type queryRowFunc func(ctx context.Context, query string, args ...any) rowScanner
+
+// rowScanner is satisfied by *sql.Row
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// errRow is a row failed before the query
+type errRow struct{ err error }
+
+func (r errRow) Scan(dest ...any) error { return r.err }
+
+func queryRowOf(db Executor) queryRowFunc {
+	return func(ctx context.Context, query string, args ...any) rowScanner {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+}
+
+// Preparer creates prepared statements, it's satisfied by *sql.DB, *sql.Tx and *sql.Conn
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Writer is Insert and Update with a cache of prepared statements, the statements are keyed by the
+// generated SQL, which is determined by the table and the set of columns. It's safe for concurrent use.
+type Writer struct {
+	db    Preparer
+	mutex sync.Mutex
+	stmts sync.Map // map[string]*sql.Stmt
+}
+
+func NewWriter(db Preparer) *Writer {
+	return &Writer{db: db}
+}
+
+func (w *Writer) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	if stmt, ok := w.stmts.Load(query); ok {
+		return stmt.(*sql.Stmt), nil
+	}
+
+	// Serialize the preparing so that a statement is never prepared twice
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if stmt, ok := w.stmts.Load(query); ok {
+		return stmt.(*sql.Stmt), nil
+	}
+	stmt, e := w.db.PrepareContext(ctx, query)
+	if e != nil {
+		return nil, errors.Wrap(e, "Prepare statement failed")
+	}
+	w.stmts.Store(query, stmt)
+	return stmt, nil
+}
+
+func (w *Writer) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmt, e := w.stmt(ctx, query)
+	if e != nil {
+		return nil, e
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (w *Writer) queryRow(ctx context.Context, query string, args ...any) rowScanner {
+	stmt, e := w.stmt(ctx, query)
+	if e != nil {
+		return errRow{e}
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// Insert is the Insert function through a prepared statement
+func (w *Writer) Insert(ctx context.Context, table string, v any) error {
+	_, e := insert(ctx, w.exec, w.queryRow, table, nil, v, insertDefault)
+	return e
+}
+
+// InsertIfNotExists is the InsertIfNotExists function through a prepared statement
+func (w *Writer) InsertIfNotExists(ctx context.Context, table string, v any) (bool, error) {
+	return insert(ctx, w.exec, w.queryRow, table, nil, v, insertIgnore)
+}
+
+// InsertColumns is the InsertColumns function through a prepared statement
+func (w *Writer) InsertColumns(ctx context.Context, table string, columns []string, v any) error {
+	_, e := insert(ctx, w.exec, w.queryRow, table, columns, v, insertDefault)
+	return e
+}
+
+// Update is the Update function through a prepared statement
+func (w *Writer) Update(ctx context.Context, table string, columns []string, v any) error {
+	_, e := update(ctx, w.exec, table, columns, "", nil, v)
+	return e
+}
+
+// UpdateResult is the UpdateResult function through a prepared statement
+func (w *Writer) UpdateResult(ctx context.Context, table string, columns []string, v any) (int64, error) {
+	return update(ctx, w.exec, table, columns, "", nil, v)
+}
+
+// Close closes all the prepared statements, the Writer could still be used after Close
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var err error
+	w.stmts.Range(func(key, value any) bool {
+		if e := value.(*sql.Stmt).Close(); e != nil && err == nil {
+			err = e
+		}
+		w.stmts.Delete(key)
+		return true
+	})
+	return err
+}
+
+// UpdateBatch updates the rows of the elements of slice by their primary keys, the elements are structs
+// or pointers to structs. The rows are updated in a transaction through a prepared statement if db could
+// begin a transaction (*sql.DB and *sql.Conn), nothing is updated if an element fails. Otherwise the
+// elements are updated one by one and the ones before the failed element are kept.
+func UpdateBatch(ctx context.Context, db Executor, table string, columns []string, slice any) error {
+	items := reflect.ValueOf(slice)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		return errors.Wrapf(ErrNotAStruct, "Invalid slice of type %T", slice)
+	}
+
+	updateItems := func(exec execFunc) error {
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i)
+			if item.Kind() != reflect.Ptr && item.CanAddr() {
+				// The version of the element is increased in place
+				item = item.Addr()
+			}
+			if _, e := update(ctx, exec, table, columns, "", nil, item.Interface()); e != nil {
+				return errors.Wrapf(e, "Update item %d failed", i)
+			}
+		}
+		return nil
+	}
+
+	beginner, ok := db.(txBeginner)
+	if !ok || items.Len() <= 1 {
+		return updateItems(db.ExecContext)
+	}
+
+	tx, e := beginner.BeginTx(ctx, nil)
+	if e != nil {
+		return errors.Wrap(e, "Begin transaction failed")
+	}
+	w := NewWriter(tx)
+	if e := updateItems(w.exec); e != nil {
+		w.Close()
+		tx.Rollback()
+		return e
+	}
+	w.Close()
+	if e := tx.Commit(); e != nil {
+		return errors.Wrap(e, "Commit transaction failed")
+	}
+	return nil
+}
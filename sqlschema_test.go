@@ -3,8 +3,21 @@ package sqlschema
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -151,6 +164,9 @@ func TestSchemeUpdate(t *testing.T) {
 	if e := sc.Update(db, context.Background()); e != nil {
 		t.Error(e)
 	}
+	if ok, e := sc.IsInSync(db, context.Background()); e != nil || !ok {
+		t.Errorf("the updated table should be in sync: %v", e)
+	}
 }
 
 func TestSchemeRead(t *testing.T) {
@@ -162,3 +178,3066 @@ func TestSchemeRead(t *testing.T) {
 	}
 	t.Log(sc)
 }
+
+func TestPostgresCreateTable(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int    `db:"id pk ai int(11)"`
+		Name string `db:"name unique varchar(255) comment(user's)"`
+	}{})
+	sc.Name = "test"
+	sc.Dialect = Postgres
+	stmts, e := sc.Dialect.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		`CREATE TABLE IF NOT EXISTS "test" ("id" integer GENERATED BY DEFAULT AS IDENTITY NOT NULL,"name" character varying(255) NOT NULL,PRIMARY KEY ("id"))`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "idx_Name" ON "test" ("name")`,
+		`COMMENT ON COLUMN "test"."name" IS 'user''s'`,
+	}
+	if len(stmts) != len(expected) {
+		t.Fatalf("unexpected statements: %v", stmts)
+	}
+	for i := range expected {
+		if stmts[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], stmts[i])
+		}
+	}
+}
+
+func TestSQLiteCreateTable(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID    int     `db:"id pk ai int(11)"`
+		Name  string  `db:"name index varchar(255) comment(name)"`
+		Score float64 `db:"score def(0)"`
+		Data  []byte  `db:"data"`
+	}{})
+	sc.Name = "test"
+	sc.Engine = "InnoDB"
+	sc.Dialect = SQLite
+	stmts, e := sc.Dialect.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		`CREATE TABLE IF NOT EXISTS "test" ("id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,"name" TEXT NOT NULL,"score" REAL NOT NULL DEFAULT 0,"data" BLOB NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS "idx_Name" ON "test" ("name")`,
+	}
+	if len(stmts) != len(expected) {
+		t.Fatalf("unexpected statements: %v", stmts)
+	}
+	for i := range expected {
+		if stmts[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], stmts[i])
+		}
+	}
+}
+
+func TestSQLiteReadIndicesDesc(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int   `db:"id pk ai"`
+		Time int64 `db:"time index(idx_time:desc)"`
+	}{})
+	sc.Name = "test"
+	sc.Dialect = SQLite
+	db := sql.OpenDB(&rowsConnector{respond: func(query string) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "table_info"):
+			return []string{"cid", "name", "type", "notnull", "dflt_value", "pk"}, [][]driver.Value{
+				{int64(0), "id", "INTEGER", int64(1), nil, int64(1)},
+				{int64(1), "time", "INTEGER", int64(1), nil, int64(0)},
+			}
+		case strings.Contains(query, "index_list"):
+			return []string{"seq", "name", "unique", "origin", "partial"}, [][]driver.Value{{int64(0), "idx_time", int64(0), "c", int64(0)}}
+		case strings.Contains(query, "index_xinfo"):
+			return []string{"seqno", "cid", "name", "desc", "coll", "key"}, [][]driver.Value{
+				{int64(0), int64(1), "time", int64(1), "BINARY", int64(1)},
+				{int64(1), int64(-1), nil, int64(0), "BINARY", int64(0)},
+			}
+		}
+		return nil, nil
+	}})
+	defer db.Close()
+	cur := &Schema{Name: "test"}
+	if e := SQLite.ReadIndices(db, context.Background(), cur); e != nil {
+		t.Fatal(e)
+	}
+	if idx := cur.Index("idx_time"); idx == nil || len(idx.Columns) != 1 || idx.Columns[0] != "time DESC" {
+		t.Fatalf("unexpected indices %+v", cur.Indices)
+	}
+	// The descending index isn't recreated by every update
+	cur.Fields = sc.Fields
+	diff, e := sc.compare(SQLite, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.AddedIndices) != 0 || len(diff.DroppedIndices) != 0 || len(diff.ModifiedIndices) != 0 {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+}
+
+func TestSchemaFieldPointer(t *testing.T) {
+	sc := &Schema{
+		Fields:  []Field{{Name: "id", Type: "int(11)"}},
+		Indices: []Index{{Columns: []string{"id"}, Primary: true}},
+	}
+	sc.Field("id").Comment = "identifier"
+	if sc.Fields[0].Comment != "identifier" {
+		t.Error("Field should point into the schema")
+	}
+	sc.Index("PRIMARY").Columns = append(sc.Index("PRIMARY").Columns, "name")
+	if len(sc.Indices[0].Columns) != 2 {
+		t.Error("Index should point into the schema")
+	}
+}
+
+func TestSerializeError(t *testing.T) {
+	data := struct {
+		Ch chan int `db:"ch text json"`
+	}{}
+	elem := reflect.ValueOf(&data).Elem()
+	field := loadDataSchemaInfo(elem.Type()).ByColumName["ch"]
+	if _, e := field.value(elem); e == nil {
+		t.Error("expected serialize error")
+	}
+	if e := field.setValue(elem, "{"); e == nil {
+		t.Error("expected deserialize error")
+	}
+}
+
+func TestArrayRoundTrip(t *testing.T) {
+	data := struct {
+		Ints   []int64   `db:"ints text arr(,)"`
+		Floats []float32 `db:"floats text arr(;)"`
+		Strs   []string  `db:"strs text arr(,)"`
+	}{
+		Ints:   []int64{1, -2, 3},
+		Floats: []float32{1.5, 2.25},
+		Strs:   []string{"a", "b"},
+	}
+	elem := reflect.ValueOf(&data).Elem()
+	schema := loadDataSchemaInfo(elem.Type())
+	out := reflect.New(elem.Type()).Elem()
+	for _, column := range []string{"ints", "floats", "strs"} {
+		field := schema.ByColumName[column]
+		v, e := field.value(elem)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e := field.setValue(out, v.(string)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if !reflect.DeepEqual(out.Interface(), elem.Interface()) {
+		t.Errorf("expected %v, got %v", elem.Interface(), out.Interface())
+	}
+}
+
+func TestArrayEscapeDelimiter(t *testing.T) {
+	items := reflect.ValueOf([]string{"a,b", `c\`, "d"})
+	data, e := encodeArray(items, ",")
+	if e != nil {
+		t.Fatal(e)
+	}
+	arr, e := decodeArray(items.Type(), data, ",")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(arr.Interface(), items.Interface()) {
+		t.Errorf("expected %q, got %q", items.Interface(), arr.Interface())
+	}
+
+	arr, _ = decodeArray(items.Type(), `a\,b`, ",")
+	if !reflect.DeepEqual(arr.Interface(), []string{"a,b"}) {
+		t.Errorf("expected [a,b], got %q", arr.Interface())
+	}
+}
+
+func TestArrayEmpty(t *testing.T) {
+	arr, e := decodeArray(reflect.TypeOf([]int{}), "", ",")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if arr.Len() != 0 {
+		t.Errorf("expected empty slice, got %v", arr.Interface())
+	}
+}
+
+func TestSchemaTimeField(t *testing.T) {
+	sc := GetSchema(&struct {
+		CreatedAt time.Time  `db:"created_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}{})
+	for _, field := range sc.Fields {
+		if field.Type != "datetime" {
+			t.Errorf("expected datetime for %s, got %s", field.Name, field.Type)
+		}
+	}
+}
+
+func TestSchemaPointerField(t *testing.T) {
+	data := struct {
+		Name *string    `db:"name"`
+		Age  *int64     `db:"age"`
+		Seen *time.Time `db:"seen"`
+		Tags *[]string  `db:"tags arr(,)"`
+	}{}
+	sc := GetSchema(&data)
+	expected := []string{"varchar(64)", "bigint(20)", "datetime", "mediumtext"}
+	for i, field := range sc.Fields {
+		if field.Type != expected[i] || !field.Nullable {
+			t.Errorf("expected nullable %s for %s, got %+v", expected[i], field.Name, field)
+		}
+	}
+
+	elem := reflect.ValueOf(&data).Elem()
+	schema := loadDataSchemaInfo(elem.Type())
+	if v, e := schema.ByColumName["name"].value(elem); e != nil || v != nil {
+		t.Errorf("expected NULL for nil pointer, got %v", v)
+	}
+	if e := schema.ByColumName["tags"].setValue(elem, "a,b"); e != nil || len(*data.Tags) != 2 {
+		t.Errorf("expected tags to be allocated, got %v", data.Tags)
+	}
+}
+
+func TestSchemaNullTypes(t *testing.T) {
+	sc := GetSchema(&struct {
+		Name  sql.NullString  `db:"name"`
+		Age   sql.NullInt64   `db:"age"`
+		Score sql.NullFloat64 `db:"score"`
+		Seen  sql.NullTime    `db:"seen"`
+	}{})
+	expected := []string{"varchar(64)", "bigint(20)", "double", "datetime"}
+	for i, field := range sc.Fields {
+		if field.Type != expected[i] || !field.Nullable {
+			t.Errorf("expected nullable %s for %s, got %+v", expected[i], field.Name, field)
+		}
+	}
+}
+
+func TestSchemaPrimaryKeyOrder(t *testing.T) {
+	sc := GetSchema(&struct {
+		A int `db:"a pk(3)"`
+		B int `db:"b pk"`
+		C int `db:"c pk(1)"`
+		D int `db:"d pk(2)"`
+	}{})
+	if columns := sc.Index("PRIMARY").Columns; !reflect.DeepEqual(columns, []string{"c", "d", "a", "b"}) {
+		t.Errorf("unexpected primary key order %v", columns)
+	}
+}
+
+func TestQuoteTableName(t *testing.T) {
+	valid := map[string]string{
+		"users":       "`users`",
+		"app.users_2": "`app`.`users_2`",
+	}
+	for name, expected := range valid {
+		if quoted, e := quoteTableName(MySQL, name); e != nil || quoted != expected {
+			t.Errorf("expected %s for %s, got %s %v", expected, name, quoted, e)
+		}
+	}
+	for _, name := range []string{"", "a.b.c", "users`; drop table x", "a.", "us ers"} {
+		if _, e := quoteTableName(MySQL, name); !errors.Is(e, ErrInvalidIdentifier) {
+			t.Errorf("expected ErrInvalidIdentifier for %q, got %v", name, e)
+		}
+	}
+	if quoted := MySQL.QuoteIdent("a`b"); quoted != "`a``b`" {
+		t.Errorf("unexpected quoted identifier %s", quoted)
+	}
+}
+
+func TestSchemaQualifiedName(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name index"`
+	}{})
+	sc.Name = "otherdb.users"
+	field := Field{Name: "age", Type: "int"}
+	expected := map[Dialect][]string{
+		MySQL:    {"ALTER TABLE `otherdb`.`users` ADD COLUMN `age` int NOT NULL", "ALTER TABLE `otherdb`.`users` DROP INDEX `idx_Name`"},
+		Postgres: {`ALTER TABLE "otherdb"."users" ADD COLUMN "age" integer NOT NULL`, `DROP INDEX IF EXISTS "otherdb"."idx_Name"`},
+		SQLite:   {`ALTER TABLE "otherdb"."users" ADD COLUMN "age" INTEGER NOT NULL`, `DROP INDEX IF EXISTS "otherdb"."idx_Name"`},
+	}
+	for d, want := range expected {
+		add, _ := d.AddColumn(sc, &field)
+		drop, _ := d.DropIndex(sc, &sc.Indices[1])
+		if len(add) == 0 || add[0] != want[0] || len(drop) != 1 || drop[0] != want[1] {
+			t.Errorf("unexpected statements %v %v", add, drop)
+		}
+	}
+	if name := sc.ForeignKeyName(&ForeignKey{Columns: []string{"uid"}}); name != "fk_users_uid" {
+		t.Errorf("unexpected foreign key name %s", name)
+	}
+	if pragma := SQLite.(sqliteDialect).pragma(sc, "table_info", "users"); pragma != `PRAGMA "otherdb".table_info("users")` {
+		t.Errorf("unexpected pragma %s", pragma)
+	}
+}
+
+func TestFormatDefault(t *testing.T) {
+	cases := []struct {
+		field    Field
+		expected string
+	}{
+		{Field{Type: "varchar(64)", DefaultValue: "N/A"}, "'N/A'"},
+		{Field{Type: "varchar(64)", DefaultValue: "'N/A'"}, "'N/A'"},
+		{Field{Type: "varchar(64)", DefaultValue: "it's"}, `'it\'s'`},
+		{Field{Type: "varchar(64)", DefaultValue: "0"}, "'0'"},
+		{Field{Type: "int(11)", DefaultValue: "0"}, "0"},
+		{Field{Type: "decimal(10,2)", DefaultValue: "1.50"}, "1.50"},
+		{Field{Type: "timestamp", DefaultValue: "CURRENT_TIMESTAMP"}, "CURRENT_TIMESTAMP"},
+		{Field{Type: "datetime", DefaultValue: "2000-01-01 00:00:00"}, "'2000-01-01 00:00:00'"},
+	}
+	for _, c := range cases {
+		if v := formatDefault(MySQL, &c.field); v != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, v)
+		}
+	}
+
+	stored := Field{Name: "a", Type: "varchar(64)", DefaultValue: "N/A"}
+	defined := Field{Name: "a", Type: "varchar(64)", DefaultValue: "'N/A'"}
+	if !stored.Equal(&defined) {
+		t.Error("quoted and unquoted default values should be equal")
+	}
+}
+
+func TestSchemaOnUpdate(t *testing.T) {
+	sc := GetSchema(&struct {
+		UpdatedAt time.Time `db:"updated_at timestamp def(CURRENT_TIMESTAMP) onupdate(CURRENT_TIMESTAMP)"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP)"
+	if stmts[0] != expected {
+		t.Errorf("expected %s, got %s", expected, stmts[0])
+	}
+
+	stored := Field{Name: "updated_at", Type: "timestamp", DefaultValue: "current_timestamp()", OnUpdate: "CURRENT_TIMESTAMP"}
+	if !stored.Equal(&sc.Fields[0]) {
+		t.Error("on update column should not differ")
+	}
+}
+
+func TestSchemaForeignKey(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID     int `db:"id pk ai"`
+		UserID int `db:"user_id index fk(users.id,CASCADE)"`
+	}{})
+	sc.Name = "orders"
+	expected := ForeignKey{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE"}
+	if len(sc.ForeignKeys) != 1 || !sc.ForeignKeys[0].Equal(&expected) {
+		t.Fatalf("unexpected foreign keys %+v", sc.ForeignKeys)
+	}
+
+	cur := *sc
+	cur.ForeignKeys = nil
+	diff, e := sc.compare(MySQL, &cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	stmts, e := diff.Statements()
+	if e != nil {
+		t.Fatal(e)
+	}
+	add := "ALTER TABLE `orders` ADD CONSTRAINT `fk_orders_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE"
+	if len(stmts) != 1 || stmts[0] != add {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestSchemaRenameColumn(t *testing.T) {
+	cur := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "int(11)"}, {Name: "title", Type: "varchar(64)"}},
+		Indices: []Index{{Name: "idx_title", Columns: []string{"title"}}},
+	}
+	sc := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "int(11)"}, {Name: "name", Type: "varchar(64)"}},
+		Indices: []Index{{Name: "idx_title", Columns: []string{"name"}}},
+	}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{Renames: map[string]string{"title": "name"}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	stmts, e := diff.Statements()
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "ALTER TABLE `test` CHANGE COLUMN `title` `name` varchar(64) NOT NULL"
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	cur := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "int(11)"}, {Name: "title", Type: "varchar(64)"}, {Name: "legacy", Type: "int(11)"}},
+		Indices: []Index{{Name: "PRIMARY", Primary: true, Columns: []string{"id"}}, {Name: "idx_legacy", Columns: []string{"legacy"}}},
+	}
+	sc := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "int(11)"}, {Name: "title", Type: "varchar(128)"}, {Name: "name", Type: "varchar(64)"}},
+		Indices: []Index{{Name: "PRIMARY", Primary: true, Columns: []string{"id"}}, {Name: "idx_name", Columns: []string{"name"}}},
+	}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if diff.Empty() {
+		t.Fatal("diff should not be empty")
+	}
+	if len(diff.AddedColumns) != 1 || diff.AddedColumns[0].Name != "name" {
+		t.Errorf("unexpected added columns %+v", diff.AddedColumns)
+	}
+	if len(diff.DroppedColumns) != 1 || diff.DroppedColumns[0].Name != "legacy" {
+		t.Errorf("unexpected dropped columns %+v", diff.DroppedColumns)
+	}
+	if len(diff.ModifiedColumns) != 1 || diff.ModifiedColumns[0].Old.Type != "varchar(64)" || diff.ModifiedColumns[0].New.Type != "varchar(128)" {
+		t.Errorf("unexpected modified columns %+v", diff.ModifiedColumns)
+	}
+	if len(diff.AddedIndices) != 1 || diff.AddedIndices[0].Name != "idx_name" || len(diff.DroppedIndices) != 1 || diff.DroppedIndices[0].Name != "idx_legacy" {
+		t.Errorf("unexpected indices %+v %+v", diff.AddedIndices, diff.DroppedIndices)
+	}
+
+	same, e := sc.compare(MySQL, sc, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !same.Empty() {
+		t.Errorf("diff should be empty, got %+v", same)
+	}
+	if stmts, _ := same.Statements(); len(stmts) != 0 {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestSchemaDiffSafeMode(t *testing.T) {
+	cur := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "int(11)"}, {Name: "legacy", Type: "int(11)"}},
+		Indices: []Index{{Name: "idx_legacy", Columns: []string{"legacy"}}},
+	}
+	sc := &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "int(11)"}}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{SafeMode: true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.DroppedColumns) != 0 || len(diff.DroppedIndices) != 0 {
+		t.Errorf("nothing should be dropped in safe mode, got %+v %+v", diff.DroppedColumns, diff.DroppedIndices)
+	}
+	if len(diff.RefusedColumns) != 1 || diff.RefusedColumns[0].Name != "legacy" || len(diff.RefusedIndices) != 1 || diff.RefusedIndices[0].Name != "idx_legacy" {
+		t.Errorf("unexpected refused drops %+v %+v", diff.RefusedColumns, diff.RefusedIndices)
+	}
+	if stmts, _ := diff.Statements(); len(stmts) != 0 {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestSchemaCollate(t *testing.T) {
+	sc := GetSchema(&struct {
+		Key string `db:"key varchar(32) charset(utf8mb4) collate(utf8mb4_bin)"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`key` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL)"
+	if stmts[0] != expected {
+		t.Errorf("expected %s, got %s", expected, stmts[0])
+	}
+
+	stored := Field{Name: "key", Type: "varchar(32)", Charset: "utf8mb4", Collate: "utf8mb4_bin"}
+	if !stored.Equal(&sc.Fields[0]) {
+		t.Error("column with the same collation should not differ")
+	}
+	stored.Collate = "utf8mb4_general_ci"
+	if stored.Equal(&sc.Fields[0]) {
+		t.Error("column with another collation should differ")
+	}
+}
+
+func TestSchemaTimePrecision(t *testing.T) {
+	sc := GetSchema(&struct {
+		CreatedAt time.Time `db:"created_at datetime(6)"`
+		UpdatedAt time.Time `db:"updated_at timestamp(3)"`
+	}{})
+	if sc.Fields[0].Type != "datetime(6)" || sc.Fields[1].Type != "timestamp(3)" {
+		t.Fatalf("unexpected types %s, %s", sc.Fields[0].Type, sc.Fields[1].Type)
+	}
+	if typ := Postgres.ColumnType(&sc.Fields[0]); typ != "timestamp without time zone" {
+		t.Errorf("unexpected postgres type %s", typ)
+	}
+	if typ := Postgres.ColumnType(&sc.Fields[1]); typ != "timestamp(3) with time zone" {
+		t.Errorf("unexpected postgres type %s", typ)
+	}
+}
+
+func TestSchemaBoolField(t *testing.T) {
+	sc := GetSchema(&struct {
+		IsActive bool `db:"is_active"`
+	}{})
+	if sc.Fields[0].Type != "tinyint(1)" {
+		t.Errorf("unexpected type %s", sc.Fields[0].Type)
+	}
+}
+
+func TestUpsertClause(t *testing.T) {
+	expected := " ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`age`=VALUES(`age`)"
+	if clause := MySQL.UpsertClause([]string{"id"}, []string{"name", "age"}); clause != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	expected = ` ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","age"=EXCLUDED."age"`
+	if clause := Postgres.UpsertClause([]string{"id"}, []string{"name", "age"}); clause != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	expected = ` ON CONFLICT ("id") DO NOTHING`
+	if clause := SQLite.UpsertClause([]string{"id"}, nil); clause != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+}
+
+// recordExecutor records the executed statements instead of running them
+type recordExecutor struct {
+	queries []string
+	args    [][]any
+	result  recordResult
+}
+
+type recordResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r recordResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r recordResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+func (r *recordExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args)
+	return r.result, nil
+}
+
+func (r *recordExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r *recordExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return nil
+}
+
+func TestInsertColumns(t *testing.T) {
+	data := &struct {
+		ID        int       `db:"id pk ai"`
+		Name      string    `db:"name"`
+		CreatedAt time.Time `db:"created_at def(CURRENT_TIMESTAMP)"`
+	}{Name: "foo"}
+	db := &recordExecutor{result: recordResult{lastInsertID: 7}}
+	if e := InsertColumns(context.Background(), db, "test", []string{"name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := "INSERT INTO `test` (`name`) VALUES (?)"
+	if db.queries[0] != expected || !reflect.DeepEqual(db.args[0], []any{"foo"}) {
+		t.Errorf("unexpected query %s %v", db.queries[0], db.args[0])
+	}
+	if data.ID != 7 {
+		t.Errorf("expected id 7, got %d", data.ID)
+	}
+
+	e := InsertColumns(context.Background(), db, "test", []string{"missing"}, data)
+	if !errors.Is(e, ErrUnknownColumn) {
+		t.Errorf("expected ErrUnknownColumn, got %v", e)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	columns, e := Describe(&struct {
+		ID    int      `db:"id pk ai"`
+		Name  string   `db:"name unique(uk_name) varchar(32)"`
+		Tags  []string `db:"tags arr(|)"`
+		Other int
+	}{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(columns) != 3 {
+		t.Fatalf("expected 3 columns, got %d", len(columns))
+	}
+	if c := columns[0]; c.Column != "id" || c.FieldName != "ID" || !c.PrimaryKey || !c.AutoIncrement || c.IndexType != PRIMARY_KEY {
+		t.Errorf("unexpected column %+v", c)
+	}
+	if c := columns[1]; c.Type != "varchar(32)" || c.IndexType != UNIQUE || c.IndexName != "uk_name" {
+		t.Errorf("unexpected column %+v", c)
+	}
+	if c := columns[2]; c.SerializeMethod != ARRAY || c.Delimiter != "|" || c.Type != "mediumtext" {
+		t.Errorf("unexpected column %+v", c)
+	}
+	if columns, e := Describe(1); columns != nil || !errors.Is(e, ErrNotAStruct) {
+		t.Errorf("non-struct should not be described, got %v", e)
+	}
+}
+
+func TestSchemaAddColumnPosition(t *testing.T) {
+	cur := &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "int(11)"}, {Name: "age", Type: "int(11)"}}}
+	sc := &Schema{Name: "test", Fields: []Field{{Name: "key", Type: "int(11)"}, {Name: "id", Type: "int(11)"}, {Name: "name", Type: "varchar(64)"}, {Name: "age", Type: "int(11)"}}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	stmts, e := diff.Statements()
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		"ALTER TABLE `test` ADD COLUMN `key` int(11) NOT NULL FIRST",
+		"ALTER TABLE `test` ADD COLUMN `name` varchar(64) NOT NULL AFTER `id`",
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+
+	// The rename is refused in safe mode, the column following the renamed one has no position
+	cur = &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "int(11)"}, {Name: "title", Type: "varchar(64)"}}}
+	sc = &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "int(11)"}, {Name: "name", Type: "varchar(10)"}, {Name: "extra", Type: "int(11)"}, {Name: "note", Type: "text"}}}
+	diff, e = sc.compare(MySQL, cur, &UpdateOptions{SafeMode: true, Renames: map[string]string{"title": "name"}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if stmts, e = diff.Statements(); e != nil {
+		t.Fatal(e)
+	}
+	expected = []string{
+		"ALTER TABLE `test` ADD COLUMN `extra` int(11) NOT NULL",
+		"ALTER TABLE `test` ADD COLUMN `note` text NOT NULL AFTER `extra`",
+	}
+	if len(diff.RefusedModifications) != 1 || !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+type testBase struct {
+	ID        int       `db:"id pk ai"`
+	CreatedAt time.Time `db:"created_at"`
+	Note      string    `db:"note"`
+}
+
+func TestSchemaEmbeddedStruct(t *testing.T) {
+	data := &struct {
+		testBase
+		Name string `db:"name"`
+		Note string `db:"note text"`
+	}{}
+	sc := GetSchema(data)
+	names := make([]string, 0, len(sc.Fields))
+	for _, field := range sc.Fields {
+		names = append(names, field.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "created_at", "note", "name"}) {
+		t.Fatalf("unexpected columns %v", names)
+	}
+	if sc.Field("note").Type != "text" {
+		t.Errorf("the note of the parent should shadow the embedded one, got %s", sc.Field("note").Type)
+	}
+
+	db := &recordExecutor{result: recordResult{lastInsertID: 3}}
+	data.Name = "foo"
+	if e := Insert(context.Background(), db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	expected := "INSERT INTO `test` (`created_at`,`note`,`name`) VALUES (?,?,?)"
+	if db.queries[0] != expected {
+		t.Errorf("expected %s, got %s", expected, db.queries[0])
+	}
+	if data.ID != 3 {
+		t.Errorf("expected id 3, got %d", data.ID)
+	}
+}
+
+// testDecimal keeps the text of a decimal value as is
+type testDecimal struct {
+	text string
+}
+
+func (d testDecimal) MarshalText() ([]byte, error) { return []byte(d.text), nil }
+func (d *testDecimal) UnmarshalText(b []byte) error {
+	d.text = string(b)
+	return nil
+}
+
+func TestDecimalField(t *testing.T) {
+	data := &struct {
+		Amount testDecimal  `db:"amount decimal(20,2)"`
+		Price  *testDecimal `db:"price decimal(20,2)"`
+	}{}
+	schema := loadDataSchemaInfo(reflect.TypeOf(*data))
+	amount, price := schema.ByColumName["amount"], schema.ByColumName["price"]
+	if amount.SerializeMethod != TEXT || price.SerializeMethod != TEXT {
+		t.Fatal("decimal fields should be scanned through text")
+	}
+
+	elem := reflect.ValueOf(data).Elem()
+	if e := amount.setValue(elem, "123456789012345678.91"); e != nil {
+		t.Fatal(e)
+	}
+	if e := price.setValue(elem, "0.10"); e != nil {
+		t.Fatal(e)
+	}
+	if data.Amount.text != "123456789012345678.91" || data.Price == nil || data.Price.text != "0.10" {
+		t.Errorf("unexpected values %v %v", data.Amount, data.Price)
+	}
+	if v, e := amount.value(elem); e != nil || v != "123456789012345678.91" {
+		t.Errorf("unexpected value %v %v", v, e)
+	}
+}
+
+func TestUpdateResult(t *testing.T) {
+	data := &struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}{ID: 1, Name: "foo"}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	n, e := UpdateResult(context.Background(), db, "test", nil, data)
+	if e != nil || n != 1 {
+		t.Fatalf("expected 1 row affected, got %d %v", n, e)
+	}
+	expected := "update `test` set `name`=? where `id`=?"
+	if db.queries[0] != expected || !reflect.DeepEqual(db.args[0], []any{"foo", 1}) {
+		t.Errorf("unexpected query %s %v", db.queries[0], db.args[0])
+	}
+
+	e = Update(context.Background(), db, "test", nil, &struct {
+		Name string `db:"name"`
+	}{})
+	if !errors.Is(e, ErrNoPrimaryKey) {
+		t.Errorf("expected ErrNoPrimaryKey, got %v", e)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	data := &struct {
+		ID      int    `db:"id pk ai"`
+		Name    string `db:"name"`
+		Version int    `db:"version version"`
+	}{ID: 1, Name: "foo", Version: 3}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	if e := Update(context.Background(), db, "test", nil, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := "update `test` set `name`=?,`version`=`version`+1 where `id`=? and `version`=?"
+	if db.queries[0] != expected || !reflect.DeepEqual(db.args[0], []any{"foo", 1, 3}) {
+		t.Errorf("unexpected query %s %v", db.queries[0], db.args[0])
+	}
+	if data.Version != 4 {
+		t.Errorf("expected version 4, got %d", data.Version)
+	}
+
+	db.result.rowsAffected = 0
+	if e := Update(context.Background(), db, "test", nil, data); !errors.Is(e, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", e)
+	}
+	if data.Version != 4 {
+		t.Errorf("version should not change on conflict, got %d", data.Version)
+	}
+
+	// The version of a struct passed by value is checked but not increased
+	db.result.rowsAffected = 1
+	if e := Update(context.Background(), db, "test", nil, *data); e != nil {
+		t.Fatal(e)
+	}
+	if db.queries[2] != expected || !reflect.DeepEqual(db.args[2], []any{"foo", 1, 4}) {
+		t.Errorf("unexpected query %s %v", db.queries[2], db.args[2])
+	}
+}
+
+func TestArrayDefaultDelimiter(t *testing.T) {
+	data := &struct {
+		Tags []string `db:"tags arr"`
+	}{Tags: []string{"a", "b"}}
+	schema := loadDataSchemaInfo(reflect.TypeOf(*data))
+	field := schema.ByColumName["tags"]
+	if field.SerializeDelimiter != "," {
+		t.Fatalf("expected the default delimiter, got %q", field.SerializeDelimiter)
+	}
+	elem := reflect.ValueOf(data).Elem()
+	if v, e := field.value(elem); e != nil || v != "a,b" {
+		t.Errorf("unexpected value %v %v", v, e)
+	}
+	if e := field.setValue(elem, "c,d"); e != nil || !reflect.DeepEqual(data.Tags, []string{"c", "d"}) {
+		t.Errorf("unexpected tags %v %v", data.Tags, e)
+	}
+}
+
+func TestExecStatementsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db := &recordExecutor{}
+	e := execStatements(db, ctx, []string{"ALTER TABLE `a` ADD COLUMN `b` int(11) NOT NULL"})
+	if !errors.Is(e, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", e)
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("no statement should be executed, got %v", db.queries)
+	}
+}
+
+func TestFieldEqualType(t *testing.T) {
+	cases := []struct {
+		defined, stored string
+		equal           bool
+	}{
+		{"int(11)", "int", true},
+		{"bigint(20) unsigned", "bigint unsigned", true},
+		{"tinyint(1)", "tinyint(1)", true},
+		{"tinyint(1)", "tinyint", false},
+		{"int(5) unsigned zerofill", "int unsigned zerofill", false},
+		{"varchar(64)", "varchar(32)", false},
+		{"VARCHAR(64)", "varchar(64)", true},
+		{"BIGINT(20) UNSIGNED", "bigint unsigned", true},
+		{"decimal(10, 2)", "decimal(10,2)", true},
+		{"enum('A','B')", "enum('a','b')", false},
+	}
+	for _, c := range cases {
+		defined, stored := Field{Name: "a", Type: c.defined}, Field{Name: "a", Type: c.stored}
+		if defined.Equal(&stored) != c.equal {
+			t.Errorf("%s and %s should be equal: %v", c.defined, c.stored, c.equal)
+		}
+	}
+}
+
+func TestIndexPrefixLength(t *testing.T) {
+	sc := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "name", Type: "varchar(255)"}, {Name: "age", Type: "int(11)"}},
+		Indices: []Index{{Name: "idx_name_age", Columns: []string{"name(10)", "age"}}},
+	}
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`name` varchar(255) NOT NULL,`age` int(11) NOT NULL,KEY `idx_name_age` (`name`(10),`age`))"
+	if stmts[0] != expected {
+		t.Errorf("expected %s, got %s", expected, stmts[0])
+	}
+
+	cur := *sc
+	cur.Fields = []Field{{Name: "title", Type: "varchar(255)"}, {Name: "age", Type: "int(11)"}}
+	cur.Indices = []Index{{Name: "idx_name_age", Columns: []string{"title(10)", "age"}}}
+	diff, e := sc.compare(MySQL, &cur, &UpdateOptions{Renames: map[string]string{"title": "name"}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.ModifiedIndices) != 0 {
+		t.Errorf("the prefix index should follow the renamed column, got %+v", diff.ModifiedIndices)
+	}
+}
+
+func TestIndexType(t *testing.T) {
+	sc := GetSchema(&struct {
+		Content string `db:"content text index(idx_content,fulltext)"`
+		Key     string `db:"key index(idx_key,hash)"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`content` text NOT NULL,`key` varchar(64) NOT NULL,FULLTEXT KEY `idx_content` (`content`),KEY `idx_key` (`key`) USING HASH)"
+	if stmts[0] != expected {
+		t.Errorf("expected %s, got %s", expected, stmts[0])
+	}
+	if pgIndexMethod("CREATE INDEX idx_key ON public.test USING hash (key)") != "HASH" {
+		t.Error("unexpected postgres index method")
+	}
+
+	stored := Index{Name: "idx_key", Columns: []string{"key"}, Type: "BTREE"}
+	if !stored.Equal(&Index{Name: "idx_key", Columns: []string{"key"}}) {
+		t.Error("BTREE is the default index type")
+	}
+}
+
+func TestIndexDescending(t *testing.T) {
+	sc := GetSchema(&struct {
+		UserID    int       `db:"user_id index(idx_user_time)"`
+		CreatedAt time.Time `db:"created_at index(idx_user_time:desc)"`
+	}{})
+	sc.Name = "test"
+	if !reflect.DeepEqual(sc.Indices[0].Columns, []string{"user_id", "created_at DESC"}) {
+		t.Fatalf("unexpected columns %v", sc.Indices[0].Columns)
+	}
+	stmts, e := MySQL.AddIndex(sc, &sc.Indices[0])
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "ALTER TABLE `test` ADD KEY `idx_user_time` (`user_id`,`created_at` DESC)"
+	if stmts[0] != expected {
+		t.Errorf("expected %s, got %s", expected, stmts[0])
+	}
+	columns := pgIndexColumns(`CREATE INDEX idx_user_time ON public.test USING btree (user_id, "created_at" DESC)`)
+	if !reflect.DeepEqual(columns, []string{"user_id", "created_at DESC"}) {
+		t.Errorf("unexpected postgres columns %v", columns)
+	}
+}
+
+func TestSchemaJSONType(t *testing.T) {
+	sc := GetSchema(&struct {
+		Attrs map[string]string `db:"attrs jsontype null"`
+	}{})
+	if sc.Fields[0].Type != "json" {
+		t.Fatalf("unexpected type %s", sc.Fields[0].Type)
+	}
+	if typ := Postgres.ColumnType(&sc.Fields[0]); typ != "json" {
+		t.Errorf("unexpected postgres type %s", typ)
+	}
+	if typ := SQLite.ColumnType(&sc.Fields[0]); typ != "TEXT" {
+		t.Errorf("unexpected sqlite type %s", typ)
+	}
+	stored := Field{Name: "attrs", Type: "json", Nullable: true}
+	if !stored.Equal(&sc.Fields[0]) {
+		t.Error("json column should not differ")
+	}
+}
+
+// testUUID encodes itself for the driver with a pointer receiver
+type testUUID [16]byte
+
+func (u *testUUID) Value() (driver.Value, error) { return fmt.Sprintf("%x", u[:]), nil }
+func (u *testUUID) Scan(src any) error {
+	_, e := fmt.Sscanf(fmt.Sprint(src), "%x", u)
+	return e
+}
+
+func TestSchemaValuerField(t *testing.T) {
+	data := &struct {
+		ID testUUID `db:"id"`
+	}{ID: testUUID{1}}
+	schema := loadDataSchemaInfo(reflect.TypeOf(*data))
+	field := schema.ByColumName["id"]
+	if field.SerializeMethod != NONE || field.DataStoreType != "varchar(64)" {
+		t.Fatalf("unexpected field %+v", field)
+	}
+	v, e := field.value(reflect.ValueOf(data).Elem())
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, ok := v.(driver.Valuer); !ok {
+		t.Errorf("the value should be passed as driver.Valuer, got %T", v)
+	}
+}
+
+func TestSchemaNamedBytes(t *testing.T) {
+	type raw []byte
+	schema := loadDataSchemaInfo(reflect.TypeOf(struct {
+		Data    raw             `db:"data"`
+		Payload json.RawMessage `db:"payload longblob"`
+	}{}))
+	if f := schema.ByColumName["data"]; f.DataStoreType != "blob" || f.SerializeMethod != NONE {
+		t.Errorf("unexpected field %+v", f)
+	}
+	if f := schema.ByColumName["payload"]; f.DataStoreType != "longblob" || f.SerializeMethod != NONE {
+		t.Errorf("unexpected field %+v", f)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"UserID":     "user_id",
+		"ID":         "id",
+		"HTTPServer": "http_server",
+		"CreatedAt":  "created_at",
+		"Name":       "name",
+		"Field_Name": "field_name",
+	}
+	for name, expected := range cases {
+		if s := SnakeCase(name); s != expected {
+			t.Errorf("expected %s for %s, got %s", expected, name, s)
+		}
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	NamingStrategy = SnakeCase
+	defer func() { NamingStrategy = nil }()
+	sc := GetSchema(&struct {
+		UserID int    `db:""`
+		Name   string `db:"Name"`
+	}{})
+	if sc.Fields[0].Name != "user_id" || sc.Fields[1].Name != "Name" {
+		t.Errorf("unexpected columns %s, %s", sc.Fields[0].Name, sc.Fields[1].Name)
+	}
+}
+
+func TestSchemaGoStruct(t *testing.T) {
+	sc := &Schema{
+		Name: "users",
+		Fields: []Field{
+			{Name: "id", Type: "bigint(20) unsigned", AutoIncrement: true},
+			{Name: "org_id", Type: "int(11)"},
+			{Name: "name", Type: "varchar(32)", Comment: "display name"},
+			{Name: "active", Type: "tinyint(1)", DefaultValue: "1"},
+			{Name: "deleted_at", Type: "datetime", Nullable: true},
+		},
+		Indices: []Index{
+			{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+			{Name: "uk_org_name", Unique: true, Columns: []string{"org_id", "name"}},
+			{Name: "idx_name", Columns: []string{"name"}},
+		},
+		ForeignKeys: []ForeignKey{{Columns: []string{"org_id"}, RefTable: "orgs", RefColumns: []string{"id"}, OnDelete: "CASCADE"}},
+	}
+	src, e := sc.GoStruct("User")
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "type User struct {\n" +
+		"\tID        uint64     `db:\"id bigint(20) unsigned pk ai\"`\n" +
+		"\tOrgID     int32      `db:\"org_id int(11) unique(uk_org_name) fk(orgs.id,CASCADE)\"`\n" +
+		"\tName      string     `db:\"name varchar(32) unique(uk_org_name) comment(display name)\"`\n" +
+		"\tActive    bool       `db:\"active tinyint(1) def(1)\"`\n" +
+		"\tDeletedAt *time.Time `db:\"deleted_at datetime null\"`\n" +
+		"\t// idx_name (name)\n" +
+		"}\n"
+	if src != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+
+	// The generated struct describes the same schema
+	generated := GetSchema(&struct {
+		ID        uint64     `db:"id bigint(20) unsigned pk ai"`
+		OrgID     int32      `db:"org_id int(11) unique(uk_org_name) fk(orgs.id,CASCADE)"`
+		Name      string     `db:"name varchar(32) unique(uk_org_name) comment(display name)"`
+		Active    bool       `db:"active tinyint(1) def(1)"`
+		DeletedAt *time.Time `db:"deleted_at datetime null"`
+	}{})
+	generated.Name = "users"
+	for i := range generated.Fields {
+		if !generated.Fields[i].Equal(&sc.Fields[i]) {
+			t.Errorf("unexpected field %+v", generated.Fields[i])
+		}
+	}
+	if !generated.Indices[0].Equal(&sc.Indices[0]) || !generated.Indices[1].Equal(&sc.Indices[1]) {
+		t.Errorf("unexpected indices %+v", generated.Indices)
+	}
+}
+
+// Build the struct type of the source generated by GoStruct, the field types are mapped by goTypes
+func compileGoStruct(t *testing.T, src string) reflect.Type {
+	goTypes := map[string]reflect.Type{
+		"uint64": reflect.TypeOf(uint64(0)), "int32": reflect.TypeOf(int32(0)), "string": reflect.TypeOf(""),
+		"*string": reflect.TypeOf((*string)(nil)), "time.Time": reflect.TypeOf(time.Time{}),
+	}
+	file, e := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	fields := make([]reflect.StructField, 0)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if f, ok := n.(*ast.Field); ok && f.Tag != nil {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			typ := goTypes[types.ExprString(f.Type)]
+			if typ == nil {
+				t.Fatalf("unexpected type %s", types.ExprString(f.Type))
+			}
+			fields = append(fields, reflect.StructField{Name: f.Names[0].Name, Type: typ, Tag: reflect.StructTag(tag)})
+		}
+		return true
+	})
+	return reflect.StructOf(fields)
+}
+
+func TestGoStructRoundTrip(t *testing.T) {
+	sc := &Schema{
+		Name: "users",
+		Fields: []Field{
+			{Name: "id", Type: "bigint(20) unsigned", AutoIncrement: true},
+			{Name: "first name", Type: "varchar(32)", Charset: "utf8mb4", Collate: "utf8mb4_bin", Comment: "the (given) name"},
+			{Name: "note(s)", Type: "text", Nullable: true, Comment: `a\b`},
+			{Name: "status", Type: "varchar(16)", DefaultValue: "'not set'"},
+			{Name: "signed_at", Type: "datetime(3)", DefaultValue: "CURRENT_TIMESTAMP(3)"},
+		},
+		Indices: []Index{
+			{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+			{Name: "idx_status", Columns: []string{"status DESC"}, Comment: "by the status"},
+		},
+	}
+	src, e := sc.GoStruct("User")
+	if e != nil {
+		t.Fatal(e)
+	}
+	generated := GetSchema(reflect.New(compileGoStruct(t, src)).Interface())
+	if generated == nil || len(generated.Fields) != len(sc.Fields) || len(generated.Indices) != len(sc.Indices) {
+		t.Fatalf("unexpected schema %+v of\n%s", generated, src)
+	}
+	for i := range sc.Fields {
+		f, expected := &generated.Fields[i], &sc.Fields[i]
+		if !f.Equal(expected) || f.Charset != expected.Charset || f.Collate != expected.Collate {
+			t.Errorf("unexpected field %+v of\n%s", f, src)
+		}
+	}
+	for i := range sc.Indices {
+		if !generated.Indices[i].Equal(&sc.Indices[i]) || generated.Indices[i].Comment != sc.Indices[i].Comment {
+			t.Errorf("unexpected index %+v of\n%s", generated.Indices[i], src)
+		}
+	}
+}
+
+func TestNotAStruct(t *testing.T) {
+	db := &recordExecutor{}
+	ctx := context.Background()
+	for _, v := range []any{1, map[string]any{}, []string{"a"}, nil} {
+		if _, e := ReadFromStruct(v); !errors.Is(e, ErrNotAStruct) {
+			t.Errorf("expected ErrNotAStruct for %T, got %v", v, e)
+		}
+		if GetSchema(v) != nil {
+			t.Errorf("expected nil schema for %T", v)
+		}
+		if e := Insert(ctx, db, "test", v); !errors.Is(e, ErrNotAStruct) {
+			t.Errorf("expected ErrNotAStruct from Insert for %T, got %v", v, e)
+		}
+		if e := Update(ctx, db, "test", nil, v); !errors.Is(e, ErrNotAStruct) {
+			t.Errorf("expected ErrNotAStruct from Update for %T, got %v", v, e)
+		}
+		if e := Delete(ctx, db, "test", v); !errors.Is(e, ErrNotAStruct) {
+			t.Errorf("expected ErrNotAStruct from Delete for %T, got %v", v, e)
+		}
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+}
+
+func TestNilPointer(t *testing.T) {
+	type user struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}
+	var u *user
+	db := &recordExecutor{}
+	ctx := context.Background()
+	if _, e := ReadFromStruct(u); !errors.Is(e, ErrNilPointer) {
+		t.Errorf("expected ErrNilPointer, got %v", e)
+	}
+	if e := Insert(ctx, db, "test", u); !errors.Is(e, ErrNilPointer) {
+		t.Errorf("expected ErrNilPointer from Insert, got %v", e)
+	}
+	if e := Update(ctx, db, "test", nil, u); !errors.Is(e, ErrNilPointer) {
+		t.Errorf("expected ErrNilPointer from Update, got %v", e)
+	}
+	if e := Delete(ctx, db, "test", u); !errors.Is(e, ErrNilPointer) {
+		t.Errorf("expected ErrNilPointer from Delete, got %v", e)
+	}
+	if e := Insert(ctx, db, "test", &u); !errors.Is(e, ErrNilPointer) {
+		t.Errorf("expected ErrNilPointer from Insert with a pointer to nil pointer, got %v", e)
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	data := &struct {
+		ID      int    `db:"id pk ai"`
+		Email   string `db:"email unique"`
+		Name    string `db:"name"`
+		Version int    `db:"version version"`
+	}{Email: "foo@example.com", Name: "foo"}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	if e := UpdateWhere(context.Background(), db, "test", []string{"name"}, "`email`=?", []any{data.Email}, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := "update `test` set `name`=?,`version`=`version`+1 where `email`=?"
+	if db.queries[0] != expected || !reflect.DeepEqual(db.args[0], []any{"foo", "foo@example.com"}) {
+		t.Errorf("unexpected query %s %v", db.queries[0], db.args[0])
+	}
+	if e := UpdateWhere(context.Background(), db, "test", nil, "", nil, data); !errors.Is(e, ErrEmptyCondition) {
+		t.Errorf("expected ErrEmptyCondition, got %v", e)
+	}
+}
+
+func TestSchemaDiffDropIndexOrder(t *testing.T) {
+	cur := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "integer"}, {Name: "legacy", Type: "integer"}},
+		Indices: []Index{{Name: "PRIMARY", Primary: true, Columns: []string{"id"}}, {Name: "idx_legacy", Columns: []string{"legacy"}}},
+	}
+	sc := &Schema{
+		Name:    "test",
+		Fields:  []Field{{Name: "id", Type: "integer"}},
+		Indices: []Index{{Name: "PRIMARY", Primary: true, Columns: []string{"id"}}},
+	}
+	expected := map[Dialect][]string{
+		MySQL:    {"ALTER TABLE `test` DROP INDEX `idx_legacy`", "ALTER TABLE `test` DROP `legacy`"},
+		Postgres: {`DROP INDEX IF EXISTS "idx_legacy"`, `ALTER TABLE "test" DROP COLUMN "legacy"`},
+		SQLite:   {`DROP INDEX IF EXISTS "idx_legacy"`, `ALTER TABLE "test" DROP COLUMN "legacy"`},
+	}
+	for d, want := range expected {
+		diff, e := sc.compare(d, cur, &UpdateOptions{})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if stmts, e := diff.Statements(); e != nil || !reflect.DeepEqual(stmts, want) {
+			t.Errorf("unexpected statements %v %v", stmts, e)
+		}
+	}
+}
+
+func TestSchemaDrop(t *testing.T) {
+	sc := &Schema{Name: "app.test"}
+	db := &recordExecutor{}
+	if e := sc.Drop(db, context.Background()); e != nil {
+		t.Fatal(e)
+	}
+	if e := sc.DropColumn(db, context.Background(), "legacy"); e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{"DROP TABLE IF EXISTS `app`.`test`", "ALTER TABLE `app`.`test` DROP `legacy`"}
+	if !reflect.DeepEqual(db.queries, expected) {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+	sc.Dialect = Postgres
+	if stmt := sc.DropSQL(); stmt != `DROP TABLE IF EXISTS "app"."test"` {
+		t.Errorf("unexpected statement %s", stmt)
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	DefaultDialect = Postgres
+	defer func() { DefaultDialect = MySQL }()
+
+	data := &struct {
+		ID   int    `db:"id pk"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}{ID: 1, Name: "foo", Age: 20}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	ctx := context.Background()
+	if e := Insert(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if e := Update(ctx, db, "test", nil, data); e != nil {
+		t.Fatal(e)
+	}
+	if e := Delete(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		`INSERT INTO "test" ("id","name","age") VALUES ($1,$2,$3)`,
+		`update "test" set "name"=$1,"age"=$2 where "id"=$3`,
+		`delete from "test" where "id"=$1`,
+	}
+	if !reflect.DeepEqual(db.queries, expected) {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+}
+
+func TestAutoTimestamp(t *testing.T) {
+	data := &struct {
+		ID        int        `db:"id pk ai"`
+		Name      string     `db:"name"`
+		CreatedAt time.Time  `db:"created_at autocreate"`
+		UpdatedAt *time.Time `db:"updated_at autoupdate"`
+	}{Name: "foo"}
+	db := &recordExecutor{result: recordResult{lastInsertID: 1, rowsAffected: 1}}
+	ctx := context.Background()
+	if e := InsertColumns(ctx, db, "test", []string{"name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	if data.CreatedAt.IsZero() || data.UpdatedAt == nil || !data.UpdatedAt.Equal(data.CreatedAt) {
+		t.Fatalf("expected the timestamps to be set, got %v %v", data.CreatedAt, data.UpdatedAt)
+	}
+	expected := "INSERT INTO `test` (`name`,`created_at`,`updated_at`) VALUES (?,?,?)"
+	if db.queries[0] != expected || db.args[0][1] != data.CreatedAt {
+		t.Errorf("unexpected query %s %v", db.queries[0], db.args[0])
+	}
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	data.CreatedAt = created
+	if e := Update(ctx, db, "test", []string{"name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	expected = "update `test` set `name`=?,`updated_at`=? where `id`=?"
+	if db.queries[1] != expected || data.CreatedAt != created || !data.UpdatedAt.After(created) {
+		t.Errorf("unexpected query %s %v", db.queries[1], db.args[1])
+	}
+
+	if e := Upsert(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if data.CreatedAt != created {
+		t.Errorf("autocreate should keep a set time, got %v", data.CreatedAt)
+	}
+	expected = "INSERT INTO `test` (`id`,`name`,`created_at`,`updated_at`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`updated_at`=VALUES(`updated_at`)"
+	if db.queries[2] != expected {
+		t.Errorf("unexpected query %s", db.queries[2])
+	}
+	// An update of all the columns keeps the creation time
+	if e := Update(ctx, db, "test", nil, data); e != nil {
+		t.Fatal(e)
+	}
+	expected = "update `test` set `name`=?,`updated_at`=? where `id`=?"
+	if db.queries[3] != expected {
+		t.Errorf("unexpected query %s", db.queries[3])
+	}
+}
+
+type testStamped struct {
+	Name      string    `db:"name pk"`
+	CreatedAt time.Time `db:"created_at autocreate"`
+	UpdatedAt time.Time `db:"updated_at autoupdate"`
+}
+
+func TestAutoTimestampByValue(t *testing.T) {
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	ctx := context.Background()
+	data := testStamped{Name: "foo"}
+	if e := Insert(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	// The times are only passed as the arguments
+	if created, ok := db.args[0][1].(time.Time); !ok || created.IsZero() || db.args[0][2] != created {
+		t.Errorf("unexpected args %v", db.args[0])
+	}
+	if e := Update(ctx, db, "test", nil, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := "update `test` set `updated_at`=? where `name`=?"
+	if updated, ok := db.args[1][0].(time.Time); db.queries[1] != expected || !ok || updated.IsZero() {
+		t.Errorf("unexpected query %s %v", db.queries[1], db.args[1])
+	}
+	if !data.CreatedAt.IsZero() || !data.UpdatedAt.IsZero() {
+		t.Errorf("the value should not be changed, got %+v", data)
+	}
+}
+
+// rowsConnector is a driver returning the same rows for every query, so the scanning could be tested without a database.
+// The rows are chosen by the query if respond is set.
+type rowsConnector struct {
+	columns []string
+	values  [][]driver.Value
+	respond func(query string) ([]string, [][]driver.Value)
+	queries []string
+	conns   int
+	mutex   sync.Mutex
+}
+
+type rowsConn struct{ c *rowsConnector }
+type rowsStmt struct {
+	c     *rowsConnector
+	query string
+}
+type rowsResult struct {
+	columns []string
+	values  [][]driver.Value
+	i       int
+}
+
+func (c *rowsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conns++
+	return &rowsConn{c}, nil
+}
+func (c *rowsConnector) Driver() driver.Driver { return nil }
+func (c *rowsConn) Prepare(query string) (driver.Stmt, error) {
+	c.c.mutex.Lock()
+	defer c.c.mutex.Unlock()
+	c.c.queries = append(c.c.queries, query)
+	return &rowsStmt{c.c, query}, nil
+}
+func (c *rowsConn) Close() error              { return nil }
+func (c *rowsConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+func (s *rowsStmt) Close() error              { return nil }
+func (s *rowsStmt) NumInput() int             { return -1 }
+func (s *rowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (s *rowsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.c.respond != nil {
+		columns, values := s.c.respond(s.query)
+		return &rowsResult{columns: columns, values: values}, nil
+	}
+	return &rowsResult{columns: s.c.columns, values: s.c.values}, nil
+}
+func (r *rowsResult) Columns() []string { return r.columns }
+func (r *rowsResult) Close() error      { return nil }
+
+func (r *rowsResult) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.i])
+	r.i++
+	return nil
+}
+
+// Query the rows through rowsConnector, the rows are positioned on the first row
+func queryRows(t *testing.T, columns []string, values ...[]driver.Value) *sql.Rows {
+	db := sql.OpenDB(&rowsConnector{columns: columns, values: values})
+	t.Cleanup(func() { db.Close() })
+	rows, e := db.Query("select")
+	if e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { rows.Close() })
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	return rows
+}
+
+func TestScanRowLenient(t *testing.T) {
+	data := &struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}{}
+	columns := []string{"id", "name", "extra"}
+	row := []driver.Value{int64(1), "foo", []byte("bar")}
+	if e := ScanRow(queryRows(t, columns, row), data); !errors.Is(e, ErrUnknownColumn) {
+		t.Errorf("expected ErrUnknownColumn, got %v", e)
+	}
+	if e := ScanRowLenient(queryRows(t, columns, row), data); e != nil {
+		t.Fatal(e)
+	}
+	if data.ID != 1 || data.Name != "foo" {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
+
+func TestScanRowPartial(t *testing.T) {
+	data := &struct {
+		ID    int               `db:"id pk ai"`
+		Name  string            `db:"name"`
+		Age   int               `db:"age"`
+		Tags  []string          `db:"tags arr"`
+		Extra map[string]string `db:"extra json"`
+	}{Age: 20, Tags: []string{"a"}}
+	if e := ScanRow(queryRows(t, []string{"name", "id"}, []driver.Value{"foo", int64(2)}), data); e != nil {
+		t.Fatal(e)
+	}
+	if data.ID != 2 || data.Name != "foo" || data.Age != 20 || !reflect.DeepEqual(data.Tags, []string{"a"}) || data.Extra != nil {
+		t.Errorf("unexpected data %+v", data)
+	}
+	if e := ScanRow(queryRows(t, []string{"extra"}, []driver.Value{`{"k":"v"}`}), data); e != nil {
+		t.Fatal(e)
+	}
+	if data.Name != "foo" || data.Extra["k"] != "v" {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	type item struct {
+		ID      int    `db:"id pk"`
+		Name    string `db:"name"`
+		Version int    `db:"version version"`
+	}
+	items := []item{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	if e := UpdateBatch(context.Background(), db, "test", []string{"name"}, items); e != nil {
+		t.Fatal(e)
+	}
+	expected := "update `test` set `name`=?,`version`=`version`+1 where `id`=? and `version`=?"
+	if len(db.queries) != 2 || db.queries[1] != expected || !reflect.DeepEqual(db.args[1], []any{"b", 2, 0}) {
+		t.Errorf("unexpected queries %v %v", db.queries, db.args)
+	}
+	if items[0].Version != 1 || items[1].Version != 1 {
+		t.Errorf("expected the versions to be increased, got %+v", items)
+	}
+
+	db.result.rowsAffected = 0
+	if e := UpdateBatch(context.Background(), db, "test", nil, []*item{&items[0]}); !errors.Is(e, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", e)
+	}
+	if e := UpdateBatch(context.Background(), db, "test", nil, items[0]); !errors.Is(e, ErrNotAStruct) {
+		t.Errorf("expected ErrNotAStruct, got %v", e)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	type item struct {
+		ID   int    `db:"id pk"`
+		Name string `db:"name"`
+	}
+	connector := &rowsConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(1)
+	w := NewWriter(db)
+	ctx := context.Background()
+
+	// The statement is prepared once and reused by the calls with the same columns
+	for i := 1; i <= 3; i++ {
+		if e := w.Insert(ctx, "test", &item{ID: i, Name: "foo"}); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if len(connector.queries) != 1 || connector.queries[0] != "INSERT INTO `test` (`id`,`name`) VALUES (?,?)" {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if e := w.Update(ctx, "test", []string{"name"}, &item{ID: i, Name: "bar"}); e != nil {
+				t.Error(e)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(connector.queries) != 2 || connector.queries[1] != "update `test` set `name`=? where `id`=?" {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+
+	// The statements are prepared again after Close
+	if e := w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	stmts := 0
+	w.stmts.Range(func(key, value any) bool {
+		stmts++
+		return true
+	})
+	if stmts != 0 {
+		t.Errorf("expected the statements to be closed, got %d", stmts)
+	}
+	if e := w.Insert(ctx, "test", &item{ID: 4, Name: "foo"}); e != nil {
+		t.Fatal(e)
+	}
+	if len(connector.queries) != 3 || connector.queries[2] != connector.queries[0] {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+}
+
+func TestCreateSQL(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name varchar(32) index"`
+	}{})
+	sc.Name = "test"
+	// The schema without a dialect uses DefaultDialect
+	cases := []struct {
+		dialect Dialect
+		sql     string
+	}{
+		{nil, "CREATE TABLE IF NOT EXISTS `test` (`id` bigint(20) NOT NULL AUTO_INCREMENT,`name` varchar(32) NOT NULL,PRIMARY KEY (`id`),KEY `idx_Name` (`name`))"},
+		{Postgres, `CREATE TABLE IF NOT EXISTS "test" ("id" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL,"name" character varying(32) NOT NULL,PRIMARY KEY ("id"));` + "\n" +
+			`CREATE INDEX IF NOT EXISTS "idx_Name" ON "test" ("name")`},
+		{SQLite, `CREATE TABLE IF NOT EXISTS "test" ("id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,"name" TEXT NOT NULL);` + "\n" +
+			`CREATE INDEX IF NOT EXISTS "idx_Name" ON "test" ("name")`},
+	}
+	for _, c := range cases {
+		sc.Dialect = c.dialect
+		stmts, e := sc.CreateSQL()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if stmts != c.sql {
+			t.Errorf("unexpected statements of %T:\n%s", c.dialect, stmts)
+		}
+	}
+}
+
+func TestMySQLANSIQuotes(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name index"`
+	}{})
+	sc.Name = "test"
+	sc.Dialect = MySQLANSI
+	stmts, e := sc.Dialect.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := `CREATE TABLE IF NOT EXISTS "test" ("id" bigint(20) NOT NULL AUTO_INCREMENT,"name" varchar(64) NOT NULL,PRIMARY KEY ("id"),KEY "idx_Name" ("name"))`
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+
+	DefaultDialect = MySQLANSI
+	defer func() { DefaultDialect = MySQL }()
+	db := &recordExecutor{}
+	if e := InsertColumns(context.Background(), db, "app.test", []string{"name"}, &struct {
+		Name string `db:"name"`
+	}{}); e != nil {
+		t.Fatal(e)
+	}
+	if db.queries[0] != `INSERT INTO "app"."test" ("name") VALUES (?)` {
+		t.Errorf("unexpected query %s", db.queries[0])
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	type cached struct {
+		UserName string `db:""`
+	}
+	ClearSchemaCache()
+	WarmSchemaCache((*cached)(nil), 1)
+	if _, ok := dataSchemaCache.Load(reflect.TypeOf(cached{})); !ok {
+		t.Fatal("expected the type to be cached")
+	}
+
+	NamingStrategy = SnakeCase
+	defer func() {
+		NamingStrategy = nil
+		ClearSchemaCache()
+	}()
+	if columns, _ := Describe(cached{}); columns[0].Column != "UserName" {
+		t.Errorf("expected the cached column name, got %s", columns[0].Column)
+	}
+	ClearSchemaCache()
+	if columns, _ := Describe(cached{}); columns[0].Column != "user_name" {
+		t.Errorf("expected the column name by the naming strategy, got %s", columns[0].Column)
+	}
+}
+
+func TestUniqueNullsNotDistinct(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID    int     `db:"id pk ai"`
+		Email *string `db:"email unique(uk_email) nullsequal"`
+	}{})
+	sc.Name = "test"
+	if index := sc.Index("uk_email"); index == nil || !index.NullsNotDistinct {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	stmts, e := Postgres.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := `CREATE UNIQUE INDEX IF NOT EXISTS "uk_email" ON "test" ("email") NULLS NOT DISTINCT`
+	if len(stmts) != 2 || stmts[1] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	if _, e := MySQL.CreateTable(sc); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", e)
+	}
+	if _, e := SQLite.AddIndex(sc, sc.Index("uk_email")); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", e)
+	}
+	if def := `CREATE UNIQUE INDEX uk_email ON public.test USING btree (email) NULLS NOT DISTINCT`; !reflect.DeepEqual(pgIndexColumns(def), []string{"email"}) {
+		t.Errorf("unexpected columns %v", pgIndexColumns(def))
+	}
+}
+
+func TestSpatialTypes(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID       int    `db:"id pk ai"`
+		Location []byte `db:"location point index(idx_location,spatial)"`
+		Area     []byte `db:"area polygon null"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`id` bigint(20) NOT NULL AUTO_INCREMENT,`location` point NOT NULL,`area` polygon NULL,PRIMARY KEY (`id`),SPATIAL KEY `idx_location` (`location`))"
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	cur := Field{Name: "location", Type: "POINT"}
+	if !cur.Equal(sc.Field("location")) {
+		t.Errorf("point column should not differ")
+	}
+	if typ := SQLite.ColumnType(sc.Field("area")); typ != "BLOB" {
+		t.Errorf("unexpected type %s", typ)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	cases := map[string]string{
+		"":                       "",
+		"O'Brien":                `O\'Brien`,
+		`C:\path`:                `C:\\path`,
+		`say "hi"`:               `say \"hi\"`,
+		"line1\nline2\r\n":       `line1\nline2\r\n`,
+		"nul\x00end":             `nul\0end`,
+		"ctrl\x1a":               `ctrl\Z`,
+		"中文'注释":                  `中文\'注释`,
+		`'\'; DROP TABLE x; -- `: `\'\\\'; DROP TABLE x; -- `,
+	}
+	for s, expected := range cases {
+		if escaped := escape(s); escaped != expected {
+			t.Errorf("expected %s for %q, got %s", expected, s, escaped)
+		}
+	}
+	if quoted := MySQL.QuoteString("0 for Unknown, \"1\" for Male, '2' for Female"); quoted != `'0 for Unknown, \"1\" for Male, \'2\' for Female'` {
+		t.Errorf("unexpected quoted string %s", quoted)
+	}
+}
+
+func TestIndexComment(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID    int    `db:"id pk ai"`
+		Email string `db:"email unique(uk_email) indexcomment(login)"`
+	}{})
+	sc.Name = "test"
+	if index := sc.Index("uk_email"); index == nil || index.Comment != "login" {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	stmts, _ := MySQL.AddIndex(sc, sc.Index("uk_email"))
+	if len(stmts) != 1 || stmts[0] != "ALTER TABLE `test` ADD UNIQUE KEY `uk_email` (`email`) COMMENT 'login'" {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	stmts, _ = Postgres.AddIndex(sc, sc.Index("uk_email"))
+	expected := []string{`CREATE UNIQUE INDEX IF NOT EXISTS "uk_email" ON "test" ("email")`, `COMMENT ON INDEX "uk_email" IS 'login'`}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+
+	cur := *sc.Index("uk_email")
+	cur.Comment = ""
+	if cur.Equal(sc.Index("uk_email")) {
+		t.Error("indices with different comments should differ")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	logged := make([]string, 0)
+	Logger = func(ctx context.Context, query string, args []any, err error) {
+		logged = append(logged, fmt.Sprintf("%s %v %v", query, args, err))
+	}
+	defer func() { Logger = nil }()
+
+	data := &struct {
+		ID   int    `db:"id pk"`
+		Name string `db:"name"`
+	}{ID: 1, Name: "foo"}
+	db := &recordExecutor{result: recordResult{rowsAffected: 1}}
+	ctx := context.Background()
+	if e := Insert(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if e := Delete(ctx, db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if e := (&Schema{Name: "test"}).Drop(db, ctx); e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		"INSERT INTO `test` (`id`,`name`) VALUES (?,?) [1 foo] <nil>",
+		"delete from `test` where `id`=? [1] <nil>",
+		"DROP TABLE IF EXISTS `test` [] <nil>",
+	}
+	if !reflect.DeepEqual(logged, expected) {
+		t.Errorf("unexpected log %q", logged)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	DefaultDialect = Postgres
+	defer func() { DefaultDialect = MySQL }()
+	var queries []string
+	Logger = func(ctx context.Context, query string, args []any, err error) { queries = append(queries, query) }
+	defer func() { Logger = nil }()
+
+	data := &struct {
+		ID    int    `db:"id pk ai"`
+		Name  string `db:"name"`
+		Score int    `db:"score def(10)"`
+	}{Name: "foo"}
+	db := sql.OpenDB(&rowsConnector{columns: []string{"id", "score"}, values: [][]driver.Value{{int64(5), int64(10)}}})
+	defer db.Close()
+	if e := InsertColumns(context.Background(), db, "test", []string{"name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := `INSERT INTO "test" ("name") VALUES ($1) RETURNING "id","score"`
+	if len(queries) != 1 || queries[0] != expected {
+		t.Errorf("unexpected queries %v", queries)
+	}
+	if data.ID != 5 || data.Score != 10 {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
+
+func TestBitAndBoolean(t *testing.T) {
+	data := &struct {
+		ID     int    `db:"id pk ai"`
+		Active bool   `db:"active boolean"`
+		Flag   bool   `db:"flag bit"`
+		Mask   uint16 `db:"mask bit(12)"`
+	}{}
+	sc := GetSchema(data)
+	if f := sc.Field("flag"); f.Type != "bit(1)" {
+		t.Errorf("unexpected type %s", f.Type)
+	}
+	if f := sc.Field("mask"); f.Type != "bit(12)" {
+		t.Errorf("unexpected type %s", f.Type)
+	}
+	cur := Field{Name: "active", Type: "tinyint(1)"}
+	if !cur.Equal(sc.Field("active")) {
+		t.Errorf("boolean column should not differ from tinyint(1)")
+	}
+	columns := []string{"id", "active", "flag", "mask"}
+	row := []driver.Value{int64(1), int64(1), []byte{1}, []byte{0x0a, 0xbc}}
+	if e := ScanRow(queryRows(t, columns, row), data); e != nil {
+		t.Fatal(e)
+	}
+	if !data.Active || !data.Flag || data.Mask != 0xabc {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
+
+func TestInsertIfNotExists(t *testing.T) {
+	data := &struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name unique"`
+	}{Name: "foo"}
+	db := &recordExecutor{result: recordResult{rowsAffected: 0}}
+	inserted, e := InsertIfNotExists(context.Background(), db, "test", data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if inserted || data.ID != 0 {
+		t.Errorf("unexpected insert %v %+v", inserted, data)
+	}
+	expected := "INSERT IGNORE INTO `test` (`name`) VALUES (?)"
+	if len(db.queries) != 1 || db.queries[0] != expected {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+
+	if query := Postgres.InsertIgnore(`INSERT INTO "test" ("name") VALUES ($1)`); query != `INSERT INTO "test" ("name") VALUES ($1) ON CONFLICT DO NOTHING` {
+		t.Errorf("unexpected query %s", query)
+	}
+}
+
+func TestIndexPrefix(t *testing.T) {
+	IndexPrefix, UniqueIndexPrefix = "ix_", "uk_"
+	defer func() { IndexPrefix, UniqueIndexPrefix = "idx_", "idx_" }()
+	sc := GetSchema(&struct {
+		ID    int    `db:"id pk ai"`
+		Email string `db:"email varchar(64) unique"`
+		Name  string `db:"name varchar(64) index"`
+		Code  string `db:"code varchar(16) unique(code_unique)"`
+	}{})
+	for _, name := range []string{"uk_Email", "ix_Name", "code_unique"} {
+		if sc.Index(name) == nil {
+			t.Errorf("expected index %s in %+v", name, sc.Indices)
+		}
+	}
+}
+
+func TestAutoIncrementStart(t *testing.T) {
+	sc := &Schema{
+		Name:          "test",
+		Fields:        []Field{{Name: "id", Type: "bigint(20)", AutoIncrement: true}},
+		Indices:       []Index{{Columns: []string{"id"}, Primary: true}},
+		AutoIncrement: 1000,
+	}
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`id` bigint(20) NOT NULL AUTO_INCREMENT,PRIMARY KEY (`id`)) AUTO_INCREMENT=1000"
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	if stmts, _ := MySQL.AlterTable(sc, &Schema{Name: "test", AutoIncrement: 1}); len(stmts) != 1 || stmts[0] != "ALTER TABLE `test` AUTO_INCREMENT = 1000" {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	// The counter is never lowered
+	if stmts, _ := MySQL.AlterTable(sc, &Schema{Name: "test", AutoIncrement: 2000}); len(stmts) != 0 {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestCheckConstraint(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID  int `db:"id pk ai"`
+		Age int `db:"age check(age>=0)"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`id` bigint(20) NOT NULL AUTO_INCREMENT,`age` bigint(20) NOT NULL,PRIMARY KEY (`id`),CONSTRAINT `chk_test_age` CHECK (age>=0))"
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+
+	cur := &Schema{Name: "test", Fields: sc.Fields, Indices: sc.Indices, Checks: []Check{{Name: "chk_test_age", Expression: "(`age` >= 0)"}}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !diff.Empty() {
+		t.Errorf("the rewritten check should not differ: %+v", diff)
+	}
+
+	cur.Checks[0].Expression = "(`age` > 0)"
+	diff, e = sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	stmts, e = diff.Statements()
+	want := []string{"ALTER TABLE `test` DROP CHECK `chk_test_age`", "ALTER TABLE `test` ADD CONSTRAINT `chk_test_age` CHECK (age>=0)"}
+	if e != nil || !reflect.DeepEqual(stmts, want) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// The checks are left alone if the server does not report them
+	cur.checksUnknown = true
+	if diff, _ = sc.compare(MySQL, cur, &UpdateOptions{}); !diff.Empty() {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+}
+
+func TestInsertOmitEmpty(t *testing.T) {
+	type record struct {
+		ID     int    `db:"id pk ai"`
+		Name   string `db:"name"`
+		Status string `db:"status varchar(16) def(active) omitempty"`
+	}
+	db := &recordExecutor{}
+	ctx := context.Background()
+	if e := Insert(ctx, db, "test", &record{Name: "foo"}); e != nil {
+		t.Fatal(e)
+	}
+	if e := Insert(ctx, db, "test", &record{Name: "bar", Status: "blocked"}); e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		"INSERT INTO `test` (`name`) VALUES (?)",
+		"INSERT INTO `test` (`name`,`status`) VALUES (?,?)",
+	}
+	if !reflect.DeepEqual(db.queries, expected) {
+		t.Errorf("unexpected queries %v", db.queries)
+	}
+}
+
+func TestSchemaCacheConcurrent(t *testing.T) {
+	type concurrent struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}
+	ClearSchemaCache()
+	infos := make([]*dataSchemaInfo, 16)
+	var wg sync.WaitGroup
+	for i := range infos {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			infos[i] = loadDataSchemaInfo(reflect.TypeOf(concurrent{}))
+		}(i)
+	}
+	wg.Wait()
+	for _, info := range infos {
+		if info != infos[0] {
+			t.Fatal("expected the type to be parsed once")
+		}
+	}
+}
+
+type testStatus int
+
+func TestEnum(t *testing.T) {
+	type record struct {
+		ID     int        `db:"id pk ai"`
+		Status testStatus `db:"status enum(active=1,inactive,banned)"`
+		Level  uint8      `db:"level int enum(low,high)"`
+		Plain  testStatus `db:"plain"`
+	}
+	sc := GetSchema(&record{})
+	if typ := sc.Field("status").Type; typ != "varchar(64)" {
+		t.Errorf("unexpected type %s", typ)
+	}
+	if typ := sc.Field("plain").Type; typ != "bigint(20)" {
+		t.Errorf("unexpected type %s", typ)
+	}
+
+	db := &recordExecutor{}
+	if e := Insert(context.Background(), db, "test", &record{Status: 2, Level: 1}); e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(db.args[0], []any{"inactive", int64(1), testStatus(0)}) {
+		t.Errorf("unexpected args %v", db.args[0])
+	}
+	if e := Insert(context.Background(), db, "test", &record{Status: 4}); !errors.Is(e, ErrInvalidEnum) {
+		t.Errorf("expected ErrInvalidEnum, got %v", e)
+	}
+
+	data := &record{}
+	columns := []string{"id", "status", "level", "plain"}
+	if e := ScanRow(queryRows(t, columns, []driver.Value{int64(1), "banned", int64(1), int64(7)}), data); e != nil {
+		t.Fatal(e)
+	}
+	if data.Status != 3 || data.Level != 1 || data.Plain != 7 {
+		t.Errorf("unexpected data %+v", data)
+	}
+	if e := ScanRow(queryRows(t, columns, []driver.Value{int64(1), "unknown", int64(1), int64(7)}), data); !errors.Is(e, ErrInvalidEnum) {
+		t.Errorf("expected ErrInvalidEnum, got %v", e)
+	}
+}
+
+type (
+	namedInt     int
+	namedInt8    int8
+	namedInt16   int16
+	namedInt32   int32
+	namedInt64   int64
+	namedUint    uint
+	namedUint8   uint8
+	namedUint16  uint16
+	namedUint32  uint32
+	namedUint64  uint64
+	namedFloat32 float32
+	namedFloat64 float64
+	namedBool    bool
+)
+
+func TestNamedNumericTypes(t *testing.T) {
+	type record struct {
+		Int     namedInt     `db:"int"`
+		Int8    namedInt8    `db:"int8"`
+		Int16   namedInt16   `db:"int16"`
+		Int32   namedInt32   `db:"int32"`
+		Int64   namedInt64   `db:"int64"`
+		Uint    namedUint    `db:"uint"`
+		Uint8   namedUint8   `db:"uint8"`
+		Uint16  namedUint16  `db:"uint16"`
+		Uint32  namedUint32  `db:"uint32"`
+		Uint64  namedUint64  `db:"uint64"`
+		Float32 namedFloat32 `db:"float32"`
+		Float64 namedFloat64 `db:"float64"`
+		Bool    namedBool    `db:"bool"`
+	}
+	expected := []struct {
+		column string
+		typ    string
+		value  driver.Value
+	}{
+		{"int", "bigint(20)", int64(-1)},
+		{"int8", "int(11)", int64(-8)},
+		{"int16", "int(11)", int64(-16)},
+		{"int32", "int(11)", int64(-32)},
+		{"int64", "bigint(20)", int64(-64)},
+		{"uint", "bigint(20) unsigned", int64(1)},
+		{"uint8", "int(11) unsigned", int64(8)},
+		{"uint16", "int(11) unsigned", int64(16)},
+		{"uint32", "int(11) unsigned", int64(32)},
+		{"uint64", "bigint(20) unsigned", int64(64)},
+		{"float32", "float", float64(1.5)},
+		{"float64", "double", float64(2.5)},
+		{"bool", "tinyint(1)", int64(1)},
+	}
+
+	sc := GetSchema(&record{})
+	columns := make([]string, 0, len(expected))
+	row := make([]driver.Value, 0, len(expected))
+	for _, c := range expected {
+		field := sc.Field(c.column)
+		if field == nil || field.Type != c.typ {
+			t.Errorf("unexpected field %s %+v", c.column, field)
+		}
+		columns = append(columns, c.column)
+		row = append(row, c.value)
+	}
+
+	data := &record{}
+	if e := ScanRow(queryRows(t, columns, row), data); e != nil {
+		t.Fatal(e)
+	}
+	want := record{-1, -8, -16, -32, -64, 1, 8, 16, 32, 64, 1.5, 2.5, true}
+	if *data != want {
+		t.Errorf("unexpected data %+v", data)
+	}
+
+	// The named values are converted to the driver values by their kinds
+	db := &recordExecutor{}
+	if e := Insert(context.Background(), db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	for i, arg := range db.args[0] {
+		if _, e := driver.DefaultParameterConverter.ConvertValue(arg); e != nil {
+			t.Errorf("unexpected arg %d %v: %v", i, arg, e)
+		}
+	}
+}
+
+func TestMigrateOrder(t *testing.T) {
+	users := &Schema{Name: "users"}
+	posts := &Schema{Name: "posts", ForeignKeys: []ForeignKey{{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}}
+	comments := &Schema{Name: "comments", ForeignKeys: []ForeignKey{
+		{Columns: []string{"post_id"}, RefTable: "posts", RefColumns: []string{"id"}},
+		{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+	}}
+	tags := &Schema{Name: "tags"}
+	sorted := sortByForeignKeys([]*Schema{comments, tags, posts, users})
+	expected := []*Schema{users, posts, comments, tags}
+	if !reflect.DeepEqual(sorted, expected) {
+		names := make([]string, 0, len(sorted))
+		for _, sc := range sorted {
+			names = append(names, sc.Name)
+		}
+		t.Errorf("unexpected order %v", names)
+	}
+}
+
+func TestReadColumnsOrder(t *testing.T) {
+	connector := &rowsConnector{
+		columns: []string{"COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_DEFAULT", "COLUMN_COMMENT", "EXTRA", "CHARACTER_SET_NAME", "COLLATION_NAME"},
+		values: [][]driver.Value{
+			{"id", "bigint(20)", "NO", nil, "", "auto_increment", nil, nil},
+			{"name", "varchar(64)", "NO", nil, "", "", "utf8mb4", "utf8mb4_general_ci"},
+		},
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	sc := &Schema{Name: "test"}
+	if e := MySQL.ReadColumns(db, context.Background(), sc); e != nil {
+		t.Fatal(e)
+	}
+	if len(connector.queries) != 1 || !strings.HasSuffix(connector.queries[0], "ORDER BY `ORDINAL_POSITION`") {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+	if len(sc.Fields) != 2 || sc.Fields[0].Name != "id" || sc.Fields[1].Name != "name" {
+		t.Errorf("unexpected fields %+v", sc.Fields)
+	}
+}
+
+func TestUnsignedOrder(t *testing.T) {
+	sc := GetSchema(&struct {
+		A int64 `db:"a unsigned bigint(20)"`
+		B int64 `db:"b bigint(20) unsigned"`
+		C int64 `db:"c unsigned"`
+		D uint  `db:"d unsigned"`
+	}{})
+	expected := map[string]string{"a": "bigint(20) unsigned", "b": "bigint(20) unsigned", "c": "bigint(20) unsigned", "d": "bigint(20) unsigned"}
+	for name, typ := range expected {
+		if f := sc.Field(name); f.Type != typ {
+			t.Errorf("unexpected type of %s: %s", name, f.Type)
+		}
+	}
+}
+
+// Call f with every permutation of items
+func permute(items []string, f func([]string)) {
+	var walk func(int)
+	walk = func(k int) {
+		if k == len(items) {
+			f(items)
+			return
+		}
+		for i := k; i < len(items); i++ {
+			items[k], items[i] = items[i], items[k]
+			walk(k + 1)
+			items[k], items[i] = items[i], items[k]
+		}
+	}
+	walk(0)
+}
+
+func TestParseFieldTagOrder(t *testing.T) {
+	cases := [][]string{
+		{"bigint(20)", "unsigned", "def(0)", "index(idx_n:desc)", "comment(count)"},
+		{"varchar(32)", "null", "unique(uk_code)", "charset(utf8mb4)", "collate(utf8mb4_bin)"},
+		{"text", "jsontype", "null", "comment(doc)"},
+		{"int", "pk", "ai", "unsigned"},
+		{"varchar(16)", "enum(a,b)", "omitempty", "check(n<>'')"},
+	}
+	for _, options := range cases {
+		var expected *dataSchemaField
+		permute(options, func(perm []string) {
+			field := &dataSchemaField{Name: "N"}
+			parseFieldTag(field, "n "+strings.Join(perm, " "))
+			if expected == nil {
+				expected = field
+				return
+			}
+			if !reflect.DeepEqual(field, expected) {
+				t.Errorf("tag %v parsed as %+v, expected %+v", perm, field, expected)
+			}
+		})
+	}
+
+	// The column type wins over the one implied by jsontype
+	field := &dataSchemaField{}
+	parseFieldTag(field, "doc text jsontype")
+	if field.DataStoreType != "text" || field.SerializeMethod != JSON {
+		t.Errorf("unexpected field %+v", field)
+	}
+}
+
+func TestRawType(t *testing.T) {
+	sc := GetSchema(&struct {
+		Year  int    `db:"year raw(year)"`
+		Level string `db:"level raw(enum('low','high')) def(low)"`
+		Count int    `db:"count raw(mediumint(8)) unsigned"`
+	}{})
+	expected := map[string]string{"year": "year", "level": "enum('low','high')", "count": "mediumint(8) unsigned"}
+	for name, typ := range expected {
+		if f := sc.Field(name); f.Type != typ {
+			t.Errorf("unexpected type of %s: %s", name, f.Type)
+		}
+	}
+
+	cur := &Schema{Name: "test", Fields: []Field{{Name: "level", Type: "enum('low','high')"}}}
+	src, e := cur.GoStruct("Test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(src, "`db:\"level raw(enum('low','high'))\"`") {
+		t.Errorf("unexpected struct %s", src)
+	}
+}
+
+func TestScanRowMap(t *testing.T) {
+	rows := queryRows(t, []string{"id", "name", "deleted"}, []driver.Value{int64(1), []byte("foo"), nil})
+	row, e := ScanRowMap(rows)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := map[string]any{"id": int64(1), "name": []byte("foo"), "deleted": nil}
+	if !reflect.DeepEqual(row, expected) {
+		t.Errorf("unexpected row %v", row)
+	}
+}
+
+func TestGet(t *testing.T) {
+	type record struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}
+	connector := &rowsConnector{columns: []string{"id", "name"}, values: [][]driver.Value{{int64(1), "foo"}}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	data := &record{ID: 1}
+	if e := Get(context.Background(), db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if len(connector.queries) != 1 || connector.queries[0] != "select `id`,`name` from `test` where `id`=?" {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+	if data.Name != "foo" {
+		t.Errorf("unexpected data %+v", data)
+	}
+
+	connector.values = nil
+	if e := Get(context.Background(), db, "test", &record{ID: 2}); e != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", e)
+	}
+}
+
+func TestCount(t *testing.T) {
+	var queries []string
+	var queryArgs [][]any
+	Logger = func(ctx context.Context, query string, args []any, err error) {
+		queries, queryArgs = append(queries, query), append(queryArgs, args)
+	}
+	defer func() { Logger = nil }()
+	db := sql.OpenDB(&rowsConnector{columns: []string{"count(*)"}, values: [][]driver.Value{{int64(42)}}})
+	defer db.Close()
+
+	n, e := Count(context.Background(), db, "test", "`name` = ? and `age` > ?", "foo", 18)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if n != 42 {
+		t.Errorf("expected 42, got %d", n)
+	}
+	if n, e = Count(context.Background(), db, "test", ""); e != nil || n != 42 {
+		t.Errorf("unexpected count %d %v", n, e)
+	}
+	expected := []string{"select count(*) from `test` where `name` = ? and `age` > ?", "select count(*) from `test`"}
+	if !reflect.DeepEqual(queries, expected) || !reflect.DeepEqual(queryArgs[0], []any{"foo", 18}) || len(queryArgs[1]) != 0 {
+		t.Errorf("unexpected queries %v %v", queries, queryArgs)
+	}
+}
+
+func TestList(t *testing.T) {
+	type record struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}
+	connector := &rowsConnector{
+		columns: []string{"id", "name"},
+		values:  [][]driver.Value{{int64(1), "foo"}, {int64(2), "bar"}},
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	var records []*record
+	opts := ListOptions{Where: "`name` <> ?", Args: []any{""}, OrderBy: "`id` DESC", Limit: 10, Offset: 20}
+	if e := List(context.Background(), db, "test", &records, opts); e != nil {
+		t.Fatal(e)
+	}
+	expected := "select `id`,`name` from `test` where `name` <> ? order by `id` DESC limit ? offset ?"
+	if len(connector.queries) != 1 || connector.queries[0] != expected {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+	if len(records) != 2 || *records[0] != (record{1, "foo"}) || *records[1] != (record{2, "bar"}) {
+		t.Errorf("unexpected records %v", records)
+	}
+
+	var values []record
+	if e := List(context.Background(), db, "test", &values, ListOptions{}); e != nil {
+		t.Fatal(e)
+	}
+	if len(values) != 2 || connector.queries[1] != "select `id`,`name` from `test`" {
+		t.Errorf("unexpected values %v %v", values, connector.queries)
+	}
+	if e := List(context.Background(), db, "test", values, ListOptions{}); !errors.Is(e, ErrNotAStruct) {
+		t.Errorf("expected ErrNotAStruct, got %v", e)
+	}
+}
+
+func TestTypeOnlyTag(t *testing.T) {
+	sc := GetSchema(&struct {
+		Count int    `db:"int(11) null"`
+		Body  string `db:" text"`
+		Text  string `db:"text"`
+		Code  string `db:"code varchar(16)"`
+	}{})
+	expected := map[string]string{"Count": "int(11)", "Body": "text", "text": "varchar(64)", "code": "varchar(16)"}
+	for name, typ := range expected {
+		if f := sc.Field(name); f == nil || f.Type != typ {
+			t.Errorf("unexpected field %s: %+v", name, f)
+		}
+	}
+}
+
+type hookedUser struct {
+	ID       int    `db:"id pk ai"`
+	Password string `db:"password"`
+	scanned  bool
+}
+
+func (u *hookedUser) BeforeInsert(ctx context.Context) error {
+	if u.Password == "" {
+		return errors.New("empty password")
+	}
+	u.Password = "hashed:" + u.Password
+	return nil
+}
+
+func (u *hookedUser) AfterScan(ctx context.Context) error {
+	u.scanned = true
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	db := &recordExecutor{}
+	user := &hookedUser{Password: "secret"}
+	if e := Insert(context.Background(), db, "users", user); e != nil {
+		t.Fatal(e)
+	}
+	if len(db.args) != 1 || db.args[0][0] != "hashed:secret" {
+		t.Errorf("unexpected args %v", db.args)
+	}
+	if e := Insert(context.Background(), db, "users", &hookedUser{}); e == nil || len(db.queries) != 1 {
+		t.Errorf("expected the insert to be aborted, got %v %v", e, db.queries)
+	}
+
+	scanned := &hookedUser{}
+	if e := ScanRow(queryRows(t, []string{"id", "password"}, []driver.Value{int64(1), "hashed:secret"}), scanned); e != nil {
+		t.Fatal(e)
+	}
+	if !scanned.scanned {
+		t.Error("expected AfterScan to be called")
+	}
+}
+
+type tenantBase struct {
+	TenantID int64 `db:"tenant_id unique(uk_tenant_code)"`
+}
+
+func TestCompositeIndexAcrossEmbedded(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID int64 `db:"id pk ai"`
+		tenantBase
+		Code string `db:"code varchar(16) unique(uk_tenant_code)"`
+	}{})
+	index := sc.Index("uk_tenant_code")
+	if index == nil || !index.Unique || !reflect.DeepEqual(index.Columns, []string{"tenant_id", "code"}) {
+		t.Errorf("unexpected index %+v", index)
+	}
+
+	// The columns follow the position of the embedded struct
+	sc = GetSchema(&struct {
+		ID   int64  `db:"id pk ai"`
+		Code string `db:"code varchar(16) unique(uk_tenant_code)"`
+		tenantBase
+	}{})
+	index = sc.Index("uk_tenant_code")
+	if index == nil || !index.Unique || !reflect.DeepEqual(index.Columns, []string{"code", "tenant_id"}) {
+		t.Errorf("unexpected index %+v", index)
+	}
+}
+
+func TestTimestampDefault(t *testing.T) {
+	sc := GetSchema(&struct {
+		CreatedAt time.Time  `db:"created_at timestamp"`
+		UpdatedAt time.Time  `db:"updated_at timestamp(3)"`
+		DeletedAt *time.Time `db:"deleted_at timestamp"`
+		ExpiredAt time.Time  `db:"expired_at timestamp def(2038-01-19)"`
+		StartedAt time.Time  `db:"started_at datetime"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS `test` (`created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+		"`updated_at` timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),`deleted_at` timestamp NULL," +
+		"`expired_at` timestamp NOT NULL DEFAULT '2038-01-19',`started_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	if stmts[0] != expected {
+		t.Errorf("unexpected statement %s", stmts[0])
+	}
+	// The default is only added by MySQL
+	if stmts, e = SQLite.CreateTable(sc); e != nil || strings.Contains(stmts[0], "CURRENT_TIMESTAMP") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// MySQL reports the default with the fsp
+	cur := &Schema{Name: "test", Fields: []Field{
+		{Name: "created_at", Type: "timestamp", DefaultValue: "CURRENT_TIMESTAMP"},
+		{Name: "updated_at", Type: "timestamp(3)", DefaultValue: "CURRENT_TIMESTAMP(3)"},
+		{Name: "deleted_at", Type: "timestamp", Nullable: true},
+		{Name: "expired_at", Type: "timestamp", DefaultValue: "2038-01-19"},
+		{Name: "started_at", Type: "datetime"},
+	}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	// The existing column without a default is modified to have it
+	if len(diff.ModifiedColumns) != 1 || diff.ModifiedColumns[0].New.Name != "started_at" {
+		t.Fatalf("unexpected modified columns %+v", diff.ModifiedColumns)
+	}
+	if stmts, e = diff.Statements(); e != nil || stmts[0] != "ALTER TABLE `test` MODIFY `started_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP" {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+}
+
+func TestRowFormat(t *testing.T) {
+	sc := &Schema{
+		Name:      "test",
+		Fields:    []Field{{Name: "id", Type: "bigint(20)"}},
+		Engine:    "InnoDB",
+		RowFormat: "DYNAMIC",
+	}
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if expected := "CREATE TABLE IF NOT EXISTS `test` (`id` bigint(20) NOT NULL) ENGINE=InnoDB ROW_FORMAT=DYNAMIC"; len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	if stmts, _ := MySQL.AlterTable(sc, &Schema{Name: "test", Engine: "InnoDB", RowFormat: "Dynamic"}); len(stmts) != 0 {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+	if stmts, _ := MySQL.AlterTable(sc, &Schema{Name: "test", Engine: "InnoDB", RowFormat: "Compact"}); len(stmts) != 1 || stmts[0] != "ALTER TABLE `test` ROW_FORMAT = DYNAMIC" {
+		t.Errorf("unexpected statements %v", stmts)
+	}
+}
+
+func TestColumnCharsetMigration(t *testing.T) {
+	sc := GetSchema(&struct {
+		Name  string `db:"name varchar(32) charset(utf8mb4) collate(utf8mb4_unicode_ci)"`
+		Title string `db:"title varchar(32) charset(utf8mb4)"`
+		Code  string `db:"code varchar(16)"`
+	}{})
+	sc.Name = "test"
+	// The columns read from a latin1 table
+	cur := &Schema{Name: "test", Fields: []Field{
+		{Name: "name", Type: "varchar(32)", Charset: "latin1", Collate: "latin1_swedish_ci"},
+		{Name: "title", Type: "varchar(32)", Charset: "latin1", Collate: "latin1_swedish_ci"},
+		{Name: "code", Type: "varchar(16)", Charset: "latin1", Collate: "latin1_swedish_ci"},
+	}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	stmts, e := diff.Statements()
+	expected := []string{
+		"ALTER TABLE `test` MODIFY `name` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL",
+		"ALTER TABLE `test` MODIFY `title` varchar(32) CHARACTER SET utf8mb4 NOT NULL",
+	}
+	if e != nil || !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+}
+
+func TestUnexportedField(t *testing.T) {
+	type record struct {
+		ID     int    `db:"id pk ai"`
+		secret string `db:"secret"`
+	}
+	data := &record{secret: "foo"}
+	if _, e := ReadFromStruct(data); !errors.Is(e, ErrUnexportedField) {
+		t.Errorf("expected ErrUnexportedField, got %v", e)
+	}
+	if _, e := Describe(data); !errors.Is(e, ErrUnexportedField) {
+		t.Errorf("expected ErrUnexportedField, got %v", e)
+	}
+	db := &recordExecutor{}
+	if e := Insert(context.Background(), db, "test", data); !errors.Is(e, ErrUnexportedField) || !strings.Contains(e.Error(), "secret") {
+		t.Errorf("expected ErrUnexportedField naming the field, got %v", e)
+	}
+	if e := ScanRow(queryRows(t, []string{"id"}, []driver.Value{int64(1)}), data); !errors.Is(e, ErrUnexportedField) {
+		t.Errorf("expected ErrUnexportedField, got %v", e)
+	}
+}
+
+func TestArrayUnsupportedType(t *testing.T) {
+	type item struct{ Name string }
+	type record struct {
+		ID    int    `db:"id pk"`
+		Count int    `db:"count arr"`
+		Items []item `db:"items arr"`
+	}
+	db := &recordExecutor{}
+	e := Insert(context.Background(), db, "test", &record{Items: []item{{"foo"}}})
+	if e == nil || !strings.Contains(e.Error(), "count") || !strings.Contains(e.Error(), "int") {
+		t.Errorf("expected an error naming the column and type, got %v", e)
+	}
+	e = Update(context.Background(), db, "test", []string{"items"}, &record{Items: []item{{"foo"}}})
+	if e == nil || !strings.Contains(e.Error(), "items") {
+		t.Errorf("expected an error naming the column, got %v", e)
+	}
+	e = ScanRow(queryRows(t, []string{"count"}, []driver.Value{"1,2"}), &record{})
+	if e == nil || !strings.Contains(e.Error(), "count") {
+		t.Errorf("expected an error naming the column, got %v", e)
+	}
+}
+
+func TestSchemaInSyncNormalized(t *testing.T) {
+	sc := &Schema{
+		Name: "test",
+		Fields: []Field{
+			{Name: "id", Type: "int(11)", AutoIncrement: true},
+			{Name: "flag", Type: "boolean", DefaultValue: "0"},
+			{Name: "name", Type: "VARCHAR(255)", Nullable: true},
+			{Name: "created", Type: "timestamp", DefaultValue: "CURRENT_TIMESTAMP"},
+		},
+		Indices: []Index{{Columns: []string{"id"}, Primary: true}},
+	}
+	// The forms reported by MySQL 8
+	cur := &Schema{
+		Name: "test",
+		Fields: []Field{
+			{Name: "id", Type: "int", AutoIncrement: true},
+			{Name: "flag", Type: "tinyint(1)", DefaultValue: "'0'"},
+			{Name: "name", Type: "varchar(255)", Nullable: true, DefaultValue: "NULL"},
+			{Name: "created", Type: "timestamp", DefaultValue: "current_timestamp()"},
+		},
+		Indices: []Index{{Name: "PRIMARY", Columns: []string{"id"}, Primary: true, Type: "BTREE"}},
+	}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !diff.Empty() {
+		t.Errorf("unexpected drift %+v", diff)
+	}
+}
+
+func TestParseCreateTable(t *testing.T) {
+	stmt := "CREATE TABLE `orders` (\n" +
+		"  `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `user_id` int NOT NULL,\n" +
+		"  `note` varchar(255) COLLATE utf8mb4_bin DEFAULT NULL COMMENT 'it''s a \\\\ (note)',\n" +
+		"  `status` enum('new','paid') NOT NULL DEFAULT 'new',\n" +
+		"  `amount` decimal(10,2) NOT NULL DEFAULT '0.00',\n" +
+		"  `updated` timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `uk_user_note` (`user_id`,`note`(10)),\n" +
+		"  KEY `ix_updated` (`updated` DESC) USING HASH COMMENT 'recent first',\n" +
+		"  FULLTEXT KEY `ft_note` (`note`),\n" +
+		"  CONSTRAINT `fk_orders_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE SET NULL,\n" +
+		"  CONSTRAINT `chk_orders_amount` CHECK ((`amount` >= 0)) /*!80016 NOT ENFORCED */\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=DYNAMIC COMMENT='orders, (all)'"
+	sc, e := parseCreateTable(stmt)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := &Schema{
+		Name: "orders",
+		Fields: []Field{
+			{Name: "id", Type: "bigint unsigned", AutoIncrement: true},
+			{Name: "user_id", Type: "int"},
+			{Name: "note", Type: "varchar(255)", Nullable: true, Collate: "utf8mb4_bin", Comment: "it's a \\ (note)"},
+			{Name: "status", Type: "enum('new','paid')", DefaultValue: "new"},
+			{Name: "amount", Type: "decimal(10,2)", DefaultValue: "0.00"},
+			{Name: "updated", Type: "timestamp(3)", DefaultValue: "CURRENT_TIMESTAMP(3)", OnUpdate: "CURRENT_TIMESTAMP(3)"},
+		},
+		Indices: []Index{
+			{Name: "PRIMARY", Columns: []string{"id"}, Primary: true, Type: "BTREE"},
+			{Name: "uk_user_note", Columns: []string{"user_id", "note(10)"}, Unique: true, Type: "BTREE"},
+			{Name: "ix_updated", Columns: []string{"updated DESC"}, Type: "HASH", Comment: "recent first"},
+			{Name: "ft_note", Columns: []string{"note"}, Type: "FULLTEXT"},
+		},
+		ForeignKeys:   []ForeignKey{{Name: "fk_orders_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "SET NULL", OnUpdate: "RESTRICT"}},
+		Checks:        []Check{{Name: "chk_orders_amount", Expression: "(`amount` >= 0)"}},
+		Engine:        "InnoDB",
+		Collate:       "utf8mb4_0900_ai_ci",
+		Comment:       "orders, (all)",
+		RowFormat:     "DYNAMIC",
+		AutoIncrement: 42,
+	}
+	if !reflect.DeepEqual(sc, expected) {
+		t.Errorf("unexpected schema\n%+v\n%+v", sc, expected)
+	}
+
+	// The schema read this way matches the model it was created from
+	model := GetSchema(&struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name varchar(64) null def(x) comment(who)"`
+	}{})
+	model.Name = "test"
+	sc, e = parseCreateTable("CREATE TABLE \"test\" (\n  \"id\" bigint NOT NULL AUTO_INCREMENT,\n  \"name\" varchar(64) DEFAULT 'x' COMMENT 'who',\n  PRIMARY KEY (\"id\")\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if diff, e := model.compare(MySQL, sc, &UpdateOptions{}); e != nil || !diff.Empty() {
+		t.Errorf("unexpected diff %+v %v", diff, e)
+	}
+
+	if _, e := readFromDBCreate(&recordExecutor{}, context.Background(), Postgres, "test"); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for the other dialects, got %v", e)
+	}
+
+	// A missing table is told by information_schema before SHOW CREATE TABLE fails
+	connector := &rowsConnector{columns: []string{"ENGINE"}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	if sc, e := ReadFromDBCreate(db, context.Background(), "missing"); sc != nil || e != nil {
+		t.Errorf("unexpected schema %+v %v", sc, e)
+	}
+	if len(connector.queries) != 1 || !strings.Contains(connector.queries[0], "`information_schema`.`TABLES`") {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+
+	// The checks dropped by MySQL before 8.0.16 are not added again
+	checked := GetSchema(&struct {
+		Age int `db:"age check(age>=0)"`
+	}{})
+	checked.Name = "people"
+	old := sql.OpenDB(&rowsConnector{respond: func(query string) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "'CHECK_CONSTRAINTS'"):
+			return []string{"COUNT(*)"}, [][]driver.Value{{int64(0)}}
+		case strings.Contains(query, "`information_schema`.`TABLES`"):
+			return []string{"ENGINE", "TABLE_COLLATION", "TABLE_COMMENT", "ROW_FORMAT", "AUTO_INCREMENT"}, [][]driver.Value{{"InnoDB", "utf8mb4_general_ci", "", "Dynamic", nil}}
+		case strings.HasPrefix(query, "SHOW CREATE TABLE"):
+			return []string{"Table", "Create Table"}, [][]driver.Value{{"people", "CREATE TABLE `people` (\n  `age` int NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"}}
+		}
+		return nil, nil
+	}})
+	defer old.Close()
+	cur, e := ReadFromDBCreate(old, context.Background(), "people")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if diff, e := checked.compare(MySQL, cur, &UpdateOptions{}); e != nil || len(checked.Checks) != 1 || len(diff.AddedChecks) != 0 {
+		t.Errorf("unexpected diff %+v %v", diff, e)
+	}
+}
+
+func TestExplicitDefaultNull(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID   int     `db:"id pk ai"`
+		Note *string `db:"note varchar(64) null def(NULL)"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil || !strings.Contains(stmts[0], "`note` varchar(64) NULL DEFAULT NULL") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// The servers report DEFAULT NULL like no default, the columns are in sync either way
+	for _, reported := range []string{"", "NULL", pgDefaultValue("NULL::character varying")} {
+		cur := &Schema{Name: "test", Fields: []Field{sc.Fields[0], {Name: "note", Type: "varchar(64)", Nullable: true, DefaultValue: reported}}, Indices: sc.Indices}
+		if diff, e := sc.compare(MySQL, cur, &UpdateOptions{}); e != nil || !diff.Empty() {
+			t.Errorf("unexpected diff for %q: %+v %v", reported, diff, e)
+		}
+		pgCur := cur.Fields[1]
+		pgCur.Type = Postgres.ColumnType(&pgCur)
+		if stmts, e := Postgres.ModifyColumn(sc, &sc.Fields[1], &pgCur); e != nil || len(stmts) != 0 {
+			t.Errorf("unexpected statements for %q: %v %v", reported, stmts, e)
+		}
+	}
+
+	// A column defaulting to NULL is not read back after an insert
+	DefaultDialect = Postgres
+	defer func() { DefaultDialect = MySQL }()
+	var queries []string
+	Logger = func(ctx context.Context, query string, args []any, err error) { queries = append(queries, query) }
+	defer func() { Logger = nil }()
+	data := &struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+		Note string `db:"note null def(NULL)"`
+	}{Name: "foo"}
+	db := sql.OpenDB(&rowsConnector{columns: []string{"id"}, values: [][]driver.Value{{int64(5)}}})
+	defer db.Close()
+	if e := InsertColumns(context.Background(), db, "test", []string{"name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	if len(queries) != 1 || queries[0] != `INSERT INTO "test" ("name") VALUES ($1) RETURNING "id"` {
+		t.Errorf("unexpected queries %v", queries)
+	}
+}
+
+func TestPartitioning(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID      int       `db:"id pk(1) ai"`
+		Created time.Time `db:"created pk(2) datetime"`
+	}{})
+	sc.Name = "events"
+	sc.Partitioning = &Partitioning{
+		Method:     "RANGE",
+		Expression: "YEAR(created)",
+		Partitions: []Partition{{Name: "p2023", Values: "2024"}, {Name: "pmax", Values: "MAXVALUE"}},
+	}
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil || !strings.HasSuffix(stmts[0], ") PARTITION BY RANGE (YEAR(created)) (PARTITION `p2023` VALUES LESS THAN (2024),PARTITION `pmax` VALUES LESS THAN MAXVALUE)") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+	if _, e := Postgres.CreateTable(sc); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", e)
+	}
+
+	// The partitioning read from the database, as SHOW CREATE TABLE shows it
+	cur, e := parseCreateTable("CREATE TABLE `events` (\n  `id` bigint NOT NULL AUTO_INCREMENT,\n  `created` datetime NOT NULL,\n  PRIMARY KEY (`id`,`created`)\n) ENGINE=InnoDB\n" +
+		"/*!50100 PARTITION BY RANGE (year(`created`))\n(PARTITION p2023 VALUES LESS THAN (2024) ENGINE = InnoDB,\n PARTITION pmax VALUES LESS THAN MAXVALUE ENGINE = InnoDB) */")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(cur.Partitioning, &Partitioning{Method: "RANGE", Expression: "year(`created`)", Partitions: []Partition{{"p2023", "2024"}, {"pmax", "MAXVALUE"}}}) {
+		t.Errorf("unexpected partitioning %+v", cur.Partitioning)
+	}
+	if stmts, e := MySQL.AlterTable(sc, cur); e != nil || len(stmts) != 0 {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// The partitions appended are added, the others repartition the table
+	cur.Partitioning.Partitions = cur.Partitioning.Partitions[:1]
+	stmts, e = MySQL.AlterTable(sc, cur)
+	if e != nil || !reflect.DeepEqual(stmts, []string{"ALTER TABLE `events` ADD PARTITION (PARTITION `pmax` VALUES LESS THAN MAXVALUE)"}) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+	cur.Partitioning.Partitions[0].Values = "2023"
+	stmts, e = MySQL.AlterTable(sc, cur)
+	if e != nil || len(stmts) != 1 || !strings.HasPrefix(stmts[0], "ALTER TABLE `events` PARTITION BY RANGE (YEAR(created)) (") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	sc.Partitioning = &Partitioning{Method: "LINEAR HASH", Expression: "id", Count: 4}
+	stmts, e = MySQL.AlterTable(sc, &Schema{Name: "events"})
+	if e != nil || !reflect.DeepEqual(stmts, []string{"ALTER TABLE `events` PARTITION BY LINEAR HASH (id) PARTITIONS 4"}) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+	cur, e = parseCreateTable("CREATE TABLE `events` (\n  `id` bigint NOT NULL\n) ENGINE=InnoDB\n/*!50100 PARTITION BY LINEAR HASH (`id`)\nPARTITIONS 4 */")
+	if e != nil || !sc.Partitioning.Equal(cur.Partitioning) {
+		t.Errorf("unexpected partitioning %+v %v", cur.Partitioning, e)
+	}
+	cur, e = parseCreateTable("CREATE TABLE `events` (\n  `day` date NOT NULL\n) ENGINE=InnoDB\n/*!50500 PARTITION BY LIST  COLUMNS(`day`)\n(PARTITION p1 VALUES IN ('2024-01-01','2024-01-02') ENGINE = InnoDB) */")
+	if e != nil || !reflect.DeepEqual(cur.Partitioning, &Partitioning{Method: "LIST COLUMNS", Expression: "`day`", Partitions: []Partition{{"p1", "'2024-01-01','2024-01-02'"}}}) {
+		t.Errorf("unexpected partitioning %+v %v", cur.Partitioning, e)
+	}
+}
+
+func TestClassifyTypeChange(t *testing.T) {
+	cases := []struct {
+		from, to string
+		expected TypeChange
+	}{
+		{"int(11)", "int", TypeUnchanged},
+		{"int", "bigint", TypeWidening},
+		{"bigint(20)", "int(11)", TypeNarrowing},
+		{"int unsigned", "bigint", TypeWidening},
+		{"int unsigned", "int", TypeNarrowing},
+		{"int", "int unsigned", TypeNarrowing},
+		{"tinyint(1)", "boolean", TypeUnchanged},
+		{"int", "decimal(10,0)", TypeWidening},
+		{"bigint", "decimal(10,2)", TypeNarrowing},
+		{"int", "double", TypeWidening},
+		{"bigint", "double", TypeNarrowing},
+		{"decimal(10,2)", "decimal(12,4)", TypeWidening},
+		{"decimal(10,2)", "decimal(10,4)", TypeNarrowing},
+		{"float", "double", TypeWidening},
+		{"double", "float", TypeNarrowing},
+		{"double", "int", TypeNarrowing},
+		{"varchar(255)", "varchar(64)", TypeNarrowing},
+		{"varchar(64)", "VARCHAR(255)", TypeWidening},
+		{"char(10)", "varchar(10)", TypeWidening},
+		{"varchar(255)", "text", TypeWidening},
+		{"longtext", "text", TypeNarrowing},
+		{"varbinary(16)", "blob", TypeWidening},
+		{"varchar(16)", "varbinary(16)", TypeIncompatible},
+		{"enum('a','b')", "enum('a','b','c')", TypeWidening},
+		{"enum('a','b')", "enum('a')", TypeNarrowing},
+		{"enum('new','paid')", "varchar(4)", TypeWidening},
+		{"enum('new','paid')", "varchar(3)", TypeNarrowing},
+		{"date", "datetime", TypeWidening},
+		{"datetime(3)", "datetime", TypeNarrowing},
+		{"datetime", "timestamp", TypeNarrowing},
+		{"time", "datetime", TypeIncompatible},
+		{"varchar(20)", "int", TypeIncompatible},
+		{"json", "text", TypeIncompatible},
+		{"character varying(64)", "character varying(255)", TypeWidening},
+		{"integer", "bigint", TypeWidening},
+		{"numeric(10,2)", "numeric(8,2)", TypeNarrowing},
+		{"timestamp(3) without time zone", "timestamp without time zone", TypeWidening},
+		{"timestamp without time zone", "timestamp(3) without time zone", TypeNarrowing},
+	}
+	for _, c := range cases {
+		if tc := classifyTypeChange(c.from, c.to); tc != c.expected {
+			t.Errorf("%s to %s: expected %s, got %s", c.from, c.to, c.expected, tc)
+		}
+	}
+
+	sc := &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "bigint(20)", AutoIncrement: true}, {Name: "name", Type: "varchar(64)"}, {Name: "title", Type: "varchar(255)"}}}
+	cur := &Schema{Name: "test", Fields: []Field{{Name: "id", Type: "int", AutoIncrement: true}, {Name: "name", Type: "varchar(255)"}, {Name: "label", Type: "varchar(255)"}}}
+	diff, e := sc.compare(MySQL, cur, &UpdateOptions{Renames: map[string]string{"label": "title"}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.ModifiedColumns) != 2 || diff.ModifiedColumns[0].TypeChange != TypeWidening || diff.ModifiedColumns[1].TypeChange != TypeNarrowing {
+		t.Errorf("unexpected modified columns %+v", diff.ModifiedColumns)
+	}
+	if len(diff.RenamedColumns) != 1 || diff.RenamedColumns[0].TypeChange != TypeUnchanged {
+		t.Errorf("unexpected renamed columns %+v", diff.RenamedColumns)
+	}
+
+	// The narrowing is refused in safe mode
+	diff, e = sc.compare(MySQL, cur, &UpdateOptions{SafeMode: true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.ModifiedColumns) != 1 || diff.ModifiedColumns[0].New.Name != "id" {
+		t.Errorf("unexpected modified columns %+v", diff.ModifiedColumns)
+	}
+	if len(diff.RefusedModifications) != 1 || diff.RefusedModifications[0].New.Name != "name" {
+		t.Errorf("unexpected refused modifications %+v", diff.RefusedModifications)
+	}
+}
+
+func TestPinnedConnection(t *testing.T) {
+	var _ Executor = (*sql.Conn)(nil)
+	var _ txBeginner = (*sql.Conn)(nil)
+
+	// The pool has a single connection held by conn, a statement not run on conn would block until the timeout
+	connector := &rowsConnector{columns: []string{"ENGINE"}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, e := db.Conn(ctx)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+
+	sc := GetSchema(&struct {
+		ID int `db:"id pk ai"`
+	}{})
+	sc.Name = "test"
+	if cur, e := ReadFromDB(conn, ctx, "test"); e != nil || cur != nil {
+		t.Errorf("unexpected schema %+v %v", cur, e)
+	}
+	stmts, e := sc.UpdateSQL(conn, ctx)
+	if e != nil || len(stmts) != 1 || !strings.HasPrefix(stmts[0], "CREATE TABLE") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+	if len(connector.queries) != 2 {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+}
+
+func TestMigrateWithoutForeignKeyChecks(t *testing.T) {
+	users := &Schema{Name: "users", Fields: []Field{{Name: "id", Type: "bigint(20)"}}}
+	posts := &Schema{Name: "posts", Fields: []Field{{Name: "user_id", Type: "bigint(20)"}}, ForeignKeys: []ForeignKey{{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}}
+
+	// A statement not run on the pinned connection would open another one, as no connection is kept idle
+	connector := &rowsConnector{columns: []string{"ENGINE"}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+	if e := MigrateWithOptions(db, context.Background(), &UpdateOptions{DisableForeignKeyChecks: true}, posts, users); e != nil {
+		t.Fatal(e)
+	}
+	if connector.conns != 1 {
+		t.Errorf("expected a single connection, got %d", connector.conns)
+	}
+	queries := connector.queries
+	if len(queries) != 6 || queries[0] != "SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0" ||
+		!strings.HasPrefix(queries[2], "CREATE TABLE IF NOT EXISTS `users`") || !strings.HasPrefix(queries[4], "CREATE TABLE IF NOT EXISTS `posts`") ||
+		queries[5] != "SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS" {
+		t.Errorf("unexpected queries %v", queries)
+	}
+
+	// The setting is restored after a failure
+	connector.queries = nil
+	users.Indices = []Index{{Name: "idx_id", Columns: []string{"id"}, Unique: true, NullsNotDistinct: true}}
+	if _, e := users.UpdateWithOptions(db, context.Background(), &UpdateOptions{DisableForeignKeyChecks: true}); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", e)
+	}
+	if n := len(connector.queries); n != 3 || connector.queries[n-1] != "SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS" {
+		t.Errorf("unexpected queries %v", connector.queries)
+	}
+
+	pg := &Schema{Name: "users", Dialect: Postgres}
+	if _, e := pg.UpdateWithOptions(db, context.Background(), &UpdateOptions{DisableForeignKeyChecks: true}); !errors.Is(e, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", e)
+	}
+}
+
+func TestPostgresInsertGivenID(t *testing.T) {
+	DefaultDialect = Postgres
+	defer func() { DefaultDialect = MySQL }()
+	var queries []string
+	Logger = func(ctx context.Context, query string, args []any, err error) { queries = append(queries, query) }
+	defer func() { Logger = nil }()
+
+	data := &struct {
+		ID   int    `db:"id pk ai"`
+		Name string `db:"name"`
+	}{ID: 7, Name: "foo"}
+	db := sql.OpenDB(&rowsConnector{columns: []string{"id"}, values: [][]driver.Value{{int64(7)}}})
+	defer db.Close()
+	if e := Upsert(context.Background(), db, "test", data); e != nil {
+		t.Fatal(e)
+	}
+	if e := InsertColumns(context.Background(), db, "test", []string{"id", "name"}, data); e != nil {
+		t.Fatal(e)
+	}
+	expected := []string{
+		`INSERT INTO "test" ("id","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name" RETURNING "id"`,
+		`INSERT INTO "test" ("id","name") VALUES ($1,$2) RETURNING "id"`,
+	}
+	if !reflect.DeepEqual(queries, expected) || data.ID != 7 {
+		t.Errorf("unexpected queries %v %+v", queries, data)
+	}
+
+	// The returned id could not be read into a struct passed by value
+	if e := Insert(context.Background(), db, "test", *data); !errors.Is(e, ErrNotAddressable) || len(queries) != 2 {
+		t.Errorf("expected ErrNotAddressable, got %v %v", e, queries)
+	}
+	// Nothing is returned without an auto increment column
+	if e := Insert(context.Background(), db, "test", struct {
+		Name string `db:"name"`
+	}{Name: "foo"}); e != nil {
+		t.Fatal(e)
+	}
+	if queries[2] != `INSERT INTO "test" ("name") VALUES ($1)` {
+		t.Errorf("unexpected query %s", queries[2])
+	}
+}
+
+func TestPostgresBoolean(t *testing.T) {
+	sc := GetSchema(&struct {
+		ID     int  `db:"id pk ai"`
+		Active bool `db:"active def(1)"`
+		Flag   bool `db:"flag boolean"`
+	}{})
+	sc.Name = "test"
+	stmts, e := Postgres.CreateTable(sc)
+	if e != nil || !strings.Contains(stmts[0], `"active" boolean NOT NULL DEFAULT TRUE,"flag" boolean NOT NULL`) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// The boolean columns read back are in sync
+	cur := &Schema{Name: "test", Fields: []Field{
+		{Name: "id", Type: "bigint", AutoIncrement: true},
+		{Name: "active", Type: "boolean", DefaultValue: "true"},
+		{Name: "flag", Type: "boolean"},
+	}, Indices: sc.Indices}
+	if diff, e := sc.compare(Postgres, cur, &UpdateOptions{}); e != nil || !diff.Empty() {
+		t.Errorf("unexpected diff %+v %v", diff, e)
+	}
+
+	// The columns created as smallint are converted
+	cur.Fields[1] = Field{Name: "active", Type: "smallint", DefaultValue: "1"}
+	stmts, e = Postgres.ModifyColumn(sc, &sc.Fields[1], &cur.Fields[1])
+	if e != nil || len(stmts) != 1 || stmts[0] != `ALTER TABLE "test" ALTER COLUMN "active" DROP DEFAULT, ALTER COLUMN "active" TYPE boolean USING "active"::integer::boolean, ALTER COLUMN "active" SET DEFAULT TRUE` {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+}
+
+func TestDefaultExpression(t *testing.T) {
+	cases := map[string]bool{
+		"CURRENT_TIMESTAMP":        true,
+		"CURRENT_TIMESTAMP(3)":     true,
+		"now()":                    true,
+		"(uuid())":                 true,
+		"nextval('seq'::regclass)": true,
+		"N/A (none)":               false,
+		"foo)":                     false,
+		"(a) or (b)":               false,
+		"f(')')":                   true,
+		"abc":                      false,
+	}
+	for v, expected := range cases {
+		if isDefaultExpression(v) != expected {
+			t.Errorf("%s: expected %v", v, expected)
+		}
+	}
+
+	sc := GetSchema(&struct {
+		ID   int    `db:"id pk ai"`
+		Note string `db:"note varchar(32) def(N/A(none\\))"`
+	}{})
+	sc.Name = "test"
+	stmts, e := MySQL.CreateTable(sc)
+	if e != nil || !strings.Contains(stmts[0], "DEFAULT 'N/A(none)'") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+}
+
+func TestForeignKeyQualifiedReference(t *testing.T) {
+	sc := &Schema{Name: "posts", ForeignKeys: []ForeignKey{{Columns: []string{"user_id"}, RefTable: "other.users", RefColumns: []string{"id"}}}}
+	stmts, e := MySQL.AddForeignKey(sc, &sc.ForeignKeys[0])
+	if e != nil || len(stmts) != 1 || !strings.Contains(stmts[0], "REFERENCES `other`.`users` (`id`)") {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+	stmts, e = Postgres.AddForeignKey(sc, &sc.ForeignKeys[0])
+	if e != nil || len(stmts) != 1 || !strings.Contains(stmts[0], `REFERENCES "other"."users" ("id")`) {
+		t.Errorf("unexpected statements %v %v", stmts, e)
+	}
+
+	// The referenced table is read back qualified only if it's in another schema, so the foreign keys are in sync
+	defined := GetSchema(&struct {
+		UserID  int `db:"user_id fk(other.users.id)"`
+		GroupID int `db:"group_id fk(groups.id)"`
+	}{})
+	defined.Name = "posts"
+	connector := &rowsConnector{
+		columns: []string{"CONSTRAINT_NAME", "COLUMN_NAME", "TABLE_SCHEMA", "REFERENCED_TABLE_SCHEMA", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME", "DELETE_RULE", "UPDATE_RULE"},
+		values: [][]driver.Value{
+			{"fk_posts_group_id", "group_id", "app", "app", "groups", "id", "RESTRICT", "RESTRICT"},
+			{"fk_posts_user_id", "user_id", "app", "other", "users", "id", "RESTRICT", "RESTRICT"},
+		},
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	cur := &Schema{Name: "posts"}
+	if e := MySQL.ReadForeignKeys(db, context.Background(), cur); e != nil {
+		t.Fatal(e)
+	}
+	if fk := cur.ForeignKey("fk_posts_user_id"); fk == nil || fk.RefTable != "other.users" {
+		t.Fatalf("unexpected foreign keys %+v", cur.ForeignKeys)
+	}
+	cur.Fields = defined.Fields
+	diff, e := defined.compare(MySQL, cur, &UpdateOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(diff.AddedForeignKeys) != 0 || len(diff.DroppedForeignKeys) != 0 {
+		t.Errorf("unexpected foreign keys added %+v dropped %+v", diff.AddedForeignKeys, diff.DroppedForeignKeys)
+	}
+
+	created, e := parseCreateTable("CREATE TABLE `posts` (\n  `user_id` int NOT NULL,\n" +
+		"  CONSTRAINT `fk_posts_user_id` FOREIGN KEY (`user_id`) REFERENCES `other`.`users` (`id`)\n) ENGINE=InnoDB")
+	if e != nil || len(created.ForeignKeys) != 1 || created.ForeignKeys[0].RefTable != "other.users" {
+		t.Errorf("unexpected foreign keys %+v %v", created, e)
+	}
+}